@@ -145,13 +145,15 @@ var _ Emittable = (*Lock)(nil)
 
 type Lock struct {
 	Statement
+	register flamego.Register
 }
 
-func NewLock(c string) *Lock {
+func NewLock(r flamego.Register, c string) *Lock {
 	return &Lock{
 		Statement: Statement{
 			comment: c,
 		},
+		register: r,
 	}
 }
 
@@ -170,7 +172,7 @@ func (a *Lock) EmittedSize() uint32 {
 }
 
 func (a *Lock) Instruction() flamego.Instruction {
-	return isa.NewLock()
+	return isa.NewLock(a.register)
 }
 
 var _ Addressable = (*Unlock)(nil)
@@ -178,13 +180,15 @@ var _ Emittable = (*Unlock)(nil)
 
 type Unlock struct {
 	Statement
+	register flamego.Register
 }
 
-func NewUnlock(c string) *Unlock {
+func NewUnlock(r flamego.Register, c string) *Unlock {
 	return &Unlock{
 		Statement: Statement{
 			comment: c,
 		},
+		register: r,
 	}
 }
 
@@ -203,7 +207,7 @@ func (a *Unlock) EmittedSize() uint32 {
 }
 
 func (a *Unlock) Instruction() flamego.Instruction {
-	return isa.NewUnlock()
+	return isa.NewUnlock(a.register)
 }
 
 var _ Addressable = (*Interrupt)(nil)
@@ -275,3 +279,291 @@ func (a *Uninterrupt) EmittedSize() uint32 {
 func (a *Uninterrupt) Instruction() flamego.Instruction {
 	return isa.NewUninterrupt(a.register)
 }
+
+var _ Addressable = (*Timestamp)(nil)
+var _ Emittable = (*Timestamp)(nil)
+
+type Timestamp struct {
+	Statement
+	counter  flamego.Counter
+	register flamego.Register
+}
+
+func NewTimestamp(t flamego.Counter, r flamego.Register, c string) *Timestamp {
+	return &Timestamp{
+		Statement: Statement{
+			comment: c,
+		},
+		counter:  t,
+		register: r,
+	}
+}
+
+func (a *Timestamp) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Timestamp) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Timestamp) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Timestamp) Instruction() flamego.Instruction {
+	return isa.NewTimestamp(a.counter, a.register)
+}
+
+var _ Addressable = (*Acknowledge)(nil)
+var _ Emittable = (*Acknowledge)(nil)
+
+type Acknowledge struct {
+	Statement
+	register flamego.Register
+}
+
+func NewAcknowledge(r flamego.Register, c string) *Acknowledge {
+	return &Acknowledge{
+		Statement: Statement{
+			comment: c,
+		},
+		register: r,
+	}
+}
+
+func (a *Acknowledge) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Acknowledge) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Acknowledge) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Acknowledge) Instruction() flamego.Instruction {
+	return isa.NewAcknowledge(a.register)
+}
+
+var _ Addressable = (*Send)(nil)
+var _ Emittable = (*Send)(nil)
+
+type Send struct {
+	Statement
+	context     flamego.Register
+	message     flamego.Register
+	destination flamego.Register
+}
+
+func NewSend(r1, r2, r3 flamego.Register, c string) *Send {
+	return &Send{
+		Statement: Statement{
+			comment: c,
+		},
+		context:     r1,
+		message:     r2,
+		destination: r3,
+	}
+}
+
+func (a *Send) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Send) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Send) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Send) Instruction() flamego.Instruction {
+	return isa.NewSend(a.context, a.message, a.destination)
+}
+
+var _ Addressable = (*Receive)(nil)
+var _ Emittable = (*Receive)(nil)
+
+type Receive struct {
+	Statement
+	register flamego.Register
+}
+
+func NewReceive(r flamego.Register, c string) *Receive {
+	return &Receive{
+		Statement: Statement{
+			comment: c,
+		},
+		register: r,
+	}
+}
+
+func (a *Receive) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Receive) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Receive) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Receive) Instruction() flamego.Instruction {
+	return isa.NewReceive(a.register)
+}
+
+var _ Addressable = (*Spawn)(nil)
+var _ Emittable = (*Spawn)(nil)
+
+type Spawn struct {
+	Statement
+	context     flamego.Register
+	descriptor  flamego.Register
+	destination flamego.Register
+}
+
+func NewSpawn(r1, r2, r3 flamego.Register, c string) *Spawn {
+	return &Spawn{
+		Statement: Statement{
+			comment: c,
+		},
+		context:     r1,
+		descriptor:  r2,
+		destination: r3,
+	}
+}
+
+func (a *Spawn) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Spawn) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Spawn) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Spawn) Instruction() flamego.Instruction {
+	return isa.NewSpawn(a.context, a.descriptor, a.destination)
+}
+
+var _ Addressable = (*Exit)(nil)
+var _ Emittable = (*Exit)(nil)
+
+type Exit struct {
+	Statement
+}
+
+func NewExit(c string) *Exit {
+	return &Exit{
+		Statement: Statement{
+			comment: c,
+		},
+	}
+}
+
+func (a *Exit) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Exit) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Exit) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Exit) Instruction() flamego.Instruction {
+	return isa.NewExit()
+}
+
+var _ Addressable = (*Save)(nil)
+var _ Emittable = (*Save)(nil)
+
+type Save struct {
+	Statement
+	register flamego.Register
+}
+
+func NewSave(r flamego.Register, c string) *Save {
+	return &Save{
+		Statement: Statement{
+			comment: c,
+		},
+		register: r,
+	}
+}
+
+func (a *Save) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Save) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Save) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Save) Instruction() flamego.Instruction {
+	return isa.NewSave(a.register)
+}
+
+var _ Addressable = (*Restore)(nil)
+var _ Emittable = (*Restore)(nil)
+
+type Restore struct {
+	Statement
+	register flamego.Register
+}
+
+func NewRestore(r flamego.Register, c string) *Restore {
+	return &Restore{
+		Statement: Statement{
+			comment: c,
+		},
+		register: r,
+	}
+}
+
+func (a *Restore) String() string {
+	return a.Instruction().String() + a.Statement.String()
+}
+
+func (a *Restore) Emit() []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, isa.Encode(a.Instruction()))
+	return buffer
+}
+
+func (a *Restore) EmittedSize() uint32 {
+	return flamego.InstructionSize
+}
+
+func (a *Restore) Instruction() flamego.Instruction {
+	return isa.NewRestore(a.register)
+}
@@ -147,6 +147,10 @@ func (p *parser) matchRegister() (flamego.Register, error) {
 		return flamego.RDataStart, nil
 	case "rDL":
 		return flamego.RDataLimit, nil
+	case "rIRA":
+		return flamego.RInterruptReturn, nil
+	case "rIV":
+		return flamego.RInterruptValue, nil
 	}
 	ok, err := regexp.MatchString(`r[\\d]*`, r)
 	if err != nil {
@@ -237,9 +241,17 @@ func (p *parser) matchStatement() (intermediate.Addressable, error) {
 		}
 		return intermediate.NewSignal(r, p.matchOptionalComment()), nil
 	case "lock":
-		return intermediate.NewLock(p.matchOptionalComment()), nil
+		r, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewLock(r, p.matchOptionalComment()), nil
 	case "unlock":
-		return intermediate.NewUnlock(p.matchOptionalComment()), nil
+		r, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewUnlock(r, p.matchOptionalComment()), nil
 	case "interrupt":
 		v, err := p.matchNumber()
 		if err != nil {
@@ -255,6 +267,73 @@ func (p *parser) matchStatement() (intermediate.Addressable, error) {
 			return nil, err
 		}
 		return intermediate.NewUninterrupt(r, p.matchOptionalComment()), nil
+	case "timestamp":
+		v, err := p.matchNumber()
+		if err != nil {
+			return nil, err
+		}
+		if v >= flamego.CounterCount {
+			return nil, &Error{p.lexer.Line(), fmt.Sprintf("Invalid Counter: '%d'", v)}
+		}
+		r, err := p.matchWritableRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewTimestamp(flamego.Counter(v), r, p.matchOptionalComment()), nil
+	case "acknowledge":
+		r, err := p.matchWritableRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewAcknowledge(r, p.matchOptionalComment()), nil
+	case "send":
+		r1, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		r2, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		r3, err := p.matchWritableRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewSend(r1, r2, r3, p.matchOptionalComment()), nil
+	case "receive":
+		r, err := p.matchWritableRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewReceive(r, p.matchOptionalComment()), nil
+	case "spawn":
+		r1, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		r2, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		r3, err := p.matchWritableRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewSpawn(r1, r2, r3, p.matchOptionalComment()), nil
+	case "exit":
+		return intermediate.NewExit(p.matchOptionalComment()), nil
+	case "save":
+		r, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewSave(r, p.matchOptionalComment()), nil
+	case "restore":
+		r, err := p.matchRegister()
+		if err != nil {
+			return nil, err
+		}
+		return intermediate.NewRestore(r, p.matchOptionalComment()), nil
 	case "jump":
 		l, err := p.matchLabel()
 		if err != nil {
@@ -6,7 +6,11 @@ const (
 )
 
 type Bus interface {
+	// Size returns the number of bytes transferred by a single request
 	Size() int
+	// Length returns the number of bytes transferred by the current request, more than Size for a burst
+	Length() int
+	SetLength(int)
 	IsValid(int) bool
 	SetValid(int, bool)
 	IsDirty(int) bool
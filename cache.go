@@ -41,6 +41,15 @@ const (
 	OffsetBitsL3Cache = 12
 )
 
+// Levels of the cache hierarchy, L1 is split into Instruction and Data caches.
+const (
+	CacheLevelL1I = iota
+	CacheLevelL1D
+	CacheLevelL2
+	CacheLevelL3
+	CacheLevels
+)
+
 type Cache interface {
 	Store
 	Clear(uint64)
@@ -1,5 +1,10 @@
 package flamego
 
+const (
+	// Unit: Nanoseconds
+	ClockPeriod = 1
+)
+
 type Clockable interface {
 	Clock(int)
 }
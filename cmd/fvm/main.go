@@ -7,11 +7,31 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 var (
-	memory  = flag.String("m", "", "The file to load into memory")
-	storage = flag.String("s", "", "The file to load into storage")
+	memory    = flag.String("m", "", "The file to load into memory")
+	storage   = flag.String("s", "", "The files to load into storage disks, separated by commas, each disk has its own control block; mem:<bytes> for an empty disk in memory")
+	mode      = flag.String("e", "ro", "The mode of storage; ro for read-only, rw to write to the storage file, or cow to write to an overlay leaving the storage file unchanged")
+	overlay   = flag.String("v", "", "The copy-on-write overlay files of storage, separated by commas, created if they don't exist; defaults to each storage file with a .cow suffix")
+	sector    = flag.Int("k", 1, "The sector size of storage disks in bytes, transfers must be aligned to sectors")
+	timer     = flag.Bool("t", false, "Attach a programmable timer")
+	dma       = flag.Bool("d", false, "Attach a DMA engine")
+	fast      = flag.Bool("f", false, "Fast-forward while the machine is quiescent")
+	parallel  = flag.Bool("p", false, "Clock each core on its own goroutine")
+	core      = flag.String("c", "barrel", "The core model; barrel or pipelined")
+	issue     = flag.String("i", "roundrobin", "The issue policy of barrel cores; roundrobin, skipidle, priority, or switchonmiss")
+	latency   = flag.Int("l", 0, "The latency of burst transactions, in clocks of the store")
+	bandwidth = flag.Int("b", 0, "The bandwidth of burst transactions, in bytes per clock of the store; 0 to fill cache lines one bus transfer at a time")
+	prefetch  = flag.String("x", "none", "The prefetcher of each cache; none, nextline, or stride")
+	write     = flag.String("w", "back", "The write policy of each cache, or of the L1, L2, and L3 caches separated by commas; back or through, optionally followed by +noallocate")
+	buffer    = flag.Int("y", 0, "The number of writes held in the write buffer of each cache; 0 to hold a single write")
+	size      = flag.Uint64("g", flamego.SizeMemory/flamego.MB, "The size of memory in MB, host memory is only allocated for the pages written")
+	arbitrate = flag.String("a", "roundrobin", "The arbitration of memory between the L3 cache and devices; roundrobin, cpu, or dma")
+	rom       = flag.Int("o", 0, "The number of bytes from address 0 mapped as read-only memory, stores to which fault")
+	epoch     = flag.Uint64("n", 0, "The time, in nanoseconds since the Unix epoch, at which the time counter starts")
 )
 
 func main() {
@@ -21,7 +41,65 @@ func main() {
 	}
 	flag.Parse()
 
-	machine := vm.NewMachine()
+	model, err := vm.ParseCoreModel(*core)
+	if err != nil {
+		log.Fatal(err)
+	}
+	policy, err := vm.ParseIssuePolicy(*issue)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prefetcher, err := vm.ParsePrefetcher(*prefetch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	arbitration, err := vm.ParseArbitration(*arbitrate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var writes [3]vm.WritePolicy
+	policies := strings.Split(*write, ",")
+	if len(policies) != 1 && len(policies) != len(writes) {
+		log.Fatal("Expected 1 or 3 write policies: " + *write)
+	}
+	for i := range writes {
+		p, err := vm.ParseWritePolicy(policies[i%len(policies)])
+		if err != nil {
+			log.Fatal(err)
+		}
+		p.Buffer = *buffer
+		writes[i] = p
+	}
+	// Each device needs a control block reserved by the bootloader
+	devices := 0
+	if *storage != "" {
+		devices += len(strings.Split(*storage, ","))
+	}
+	if *timer {
+		devices++
+	}
+	if *dma {
+		devices++
+	}
+	if devices > flamego.DeviceControlBlockCount {
+		log.Fatal(fmt.Sprintf("Expected at most %d devices: %d", flamego.DeviceControlBlockCount, devices))
+	}
+	machine := vm.NewMachineWithConfig(vm.Config{
+		Core:  model,
+		Issue: policy,
+		Burst: vm.Burst{
+			Latency:   *latency,
+			Bandwidth: *bandwidth,
+		},
+		Prefetch:    prefetcher,
+		Write:       writes,
+		MemorySize:  *size * flamego.MB,
+		Arbitration: arbitration,
+		Parallel:    *parallel,
+	})
+	machine.FastForward = *fast
+
+	machine.Processor.SetEpoch(*epoch)
 
 	if *memory != "" {
 		// Copy file into memory
@@ -33,21 +111,93 @@ func main() {
 		machine.Memory.Load(f)
 	}
 
+	if *rom > 0 {
+		machine.MemoryMap.Add(vm.Region{
+			Type: vm.RegionROM,
+			Size: uint64(*rom),
+		})
+	}
+
+	// Each device has a control block following that of the previous device
+	address := uint64(flamego.DeviceControlBlockAddress)
+
 	if *storage != "" {
-		s := vm.NewFileStorage(machine.Memory, flamego.DeviceControlBlockAddress)
-		if err := s.Open(*storage); err != nil {
-			log.Fatal(err)
+		var overlays []string
+		if *overlay != "" {
+			overlays = strings.Split(*overlay, ",")
+		}
+		for i, file := range strings.Split(*storage, ",") {
+			s := vm.NewFileStorage(machine.Controller.AddPort(vm.PortDMA), address)
+			s.SetSectorSize(*sector)
+			var err error
+			switch {
+			case strings.HasPrefix(file, "mem:"):
+				var n uint64
+				if n, err = strconv.ParseUint(strings.TrimPrefix(file, "mem:"), 10, 64); err == nil {
+					s.OpenMemory(make([]byte, n))
+				}
+			case *mode == "ro":
+				err = s.Open(file)
+			case *mode == "rw":
+				err = s.OpenReadWrite(file)
+			case *mode == "cow":
+				o := file + ".cow"
+				if i < len(overlays) {
+					o = overlays[i]
+				}
+				err = s.OpenOverlay(file, o)
+			default:
+				err = fmt.Errorf("Unrecognized Storage Mode: %s", *mode)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer s.Close()
+			machine.Processor.AddDevice(s)
+			log.Println("Storage Disk:", file, "Capacity:", s.Capacity(), "Control Block:", address)
+			address += flamego.DeviceControlBlockSize
 		}
-		machine.Processor.AddDevice(s)
 	}
 
-	// Signal the first context of the first core
-	machine.Processor.Signal(0)
+	// Each MMIO window follows that of the previous device, after the end of memory
+	window := machine.Memory.Size()
+
+	if *timer {
+		t := vm.NewTimer(machine.Controller.AddPort(vm.PortDMA), address)
+		machine.Processor.AddDevice(t)
+		address += flamego.DeviceControlBlockSize
+		machine.MemoryMap.Add(vm.Region{
+			Type:   vm.RegionMMIO,
+			Start:  window,
+			Size:   vm.TimerWindowSize,
+			Device: t,
+		})
+		log.Println("Timer MMIO Window:", window)
+		window += vm.TimerWindowSize
+	}
+
+	if *dma {
+		machine.Processor.AddDevice(vm.NewDMA(machine.Controller.AddPort(vm.PortDMA), address))
+		address += flamego.DeviceControlBlockSize
+	}
+
+	// Signal the first context of the first core, with the address of the device table
+	machine.Boot()
 
 	// Run until processor halts
-	var cycle int
-	for ; !machine.Processor.HasHalted(); cycle++ {
+	for !machine.Processor.HasHalted() {
 		machine.Clock()
 	}
-	log.Println("Cycles:", cycle)
+	var occupied, cycles uint64
+	for i := 0; i < flamego.CoreCount; i++ {
+		if c, ok := machine.Processor.Core(i).(*vm.Core); ok {
+			s := c.Statistics()
+			occupied += s.Occupied
+			cycles += s.Cycles * flamego.ContextCount
+		}
+	}
+	if cycles > 0 {
+		log.Printf("Occupancy: %.2f%%\n", 100*float64(occupied)/float64(cycles))
+	}
+	log.Println("Cycles:", machine.Tick)
 }
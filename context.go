@@ -2,6 +2,15 @@ package flamego
 
 const ContextCount = 8
 
+const (
+	// Unit: Messages
+	MailboxSize = 8
+	// Unit: Registers, R4 - R13
+	SpawnDescriptorLength = 10
+	// Unit: Registers, R4 - R31
+	ContextStateLength = 28
+)
+
 type Context interface {
 	Id() int
 
@@ -12,16 +21,20 @@ type Context interface {
 	IsValid() bool
 	IsAsleep() bool
 	Sleep()
+	Wait()
+	Wake()
+	Spawn(int, []uint64) bool
+	Parent() int
 	Error(InterruptValue)
 	IsInterrupted() bool
 	SetInterrupted(bool)
 	Signal()
 	IsSignalled() bool
 
-	RequiresLock() bool
-	SetRequiresLock(bool)
-	AcquiredLock() bool
-	SetAcquiredLock(bool)
+	RequiresLock(int) bool
+	SetRequiresLock(int, bool)
+	AcquiredLock(int) bool
+	SetAcquiredLock(int, bool)
 
 	FetchInstruction()
 	LoadInstruction()
@@ -31,9 +44,16 @@ type Context interface {
 	FormatData(uint64, uint64) (uint64, uint64)
 	StoreData(uint64, uint64)
 	RetireInstruction()
+	RetiredInstructions() uint64
+	State() *InstructionState
 
 	ReadRegister(Register) uint64
 	WriteRegister(Register, uint64)
 	IncrementProgramCounter()
 	SetProgramCounter(uint64)
 }
+
+// ContextIdentifier returns the processor-wide identifier of the given context.
+func ContextIdentifier(x Context) int {
+	return x.Core().Id()*ContextCount + x.Id()
+}
@@ -11,7 +11,4 @@ type Core interface {
 	Cache() Cache
 
 	AddContext(Context)
-
-	RequiresLock() bool
-	SetAcquiredLock(bool)
 }
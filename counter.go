@@ -0,0 +1,26 @@
+package flamego
+
+import "fmt"
+
+type Counter uint8
+
+const (
+	CounterCycle       Counter = iota // Cycles since the machine started
+	CounterInstruction                // Instructions retired by the context
+	CounterTime                       // Nanoseconds since the epoch
+)
+
+const CounterCount = 3
+
+func (c Counter) String() string {
+	switch c {
+	case CounterCycle:
+		return "Cycle"
+	case CounterInstruction:
+		return "Instruction"
+	case CounterTime:
+		return "Time"
+	default:
+		return fmt.Sprintf("Unrecognized Counter: %d", c)
+	}
+}
@@ -35,6 +35,9 @@ func (o DeviceOperation) String() string {
 const (
 	DeviceControlBlockAddress = 512
 	DeviceControlBlockSize    = 24
+
+	// The bootloader reserves the control blocks of this many devices, from DeviceControlBlockAddress
+	DeviceControlBlockCount = 8
 )
 
 const (
@@ -43,6 +46,140 @@ const (
 	DeviceOffsetMemoryAddress
 )
 
+// Offsets of the 64bit fields of the descriptor written to MemoryAddress by DeviceStatus, in units of DataSize.
+const (
+	DeviceStatusManufacturer    uint32 = iota
+	DeviceStatusState                  // DeviceState
+	DeviceStatusType                   // DeviceType
+	DeviceStatusHardwareVersion        // 32bit Major, 32bit Minor
+	DeviceStatusSoftwareVersion        // 32bit Major, 32bit Minor
+	DeviceStatusCapacity               // By type; storage bytes, display 32bit width and 32bit height, timer remaining clocks, DMA bytes transferred
+	DeviceStatusDetail                 // By type; storage sector size, display bytes per pixel, timer period, DMA burst size
+	DeviceStatusError                  // DeviceError of the last failed operation, cleared by DeviceStatus
+	DeviceStatusFields
+)
+
+const (
+	// Unit: Bytes
+	DeviceStatusDescriptorSize = 8 * DataSize
+
+	// ASCII "FLAMEGO"
+	DeviceManufacturer = 0x464c414d45474f00
+)
+
+type DeviceState uint64
+
+const (
+	DeviceDisabled DeviceState = iota
+	DeviceEnabled
+)
+
+type DeviceType uint64
+
+const (
+	DeviceTypeUnknown DeviceType = iota
+	DeviceTypeInterruptController
+	DeviceTypeStorage
+	DeviceTypeDisplay
+	DeviceTypeKeyboard
+	DeviceTypeTimer
+	DeviceTypeDMA
+)
+
+func (t DeviceType) String() string {
+	switch t {
+	case DeviceTypeUnknown:
+		return "Unknown"
+	case DeviceTypeInterruptController:
+		return "InterruptController"
+	case DeviceTypeStorage:
+		return "Storage"
+	case DeviceTypeDisplay:
+		return "Display"
+	case DeviceTypeKeyboard:
+		return "Keyboard"
+	case DeviceTypeTimer:
+		return "Timer"
+	case DeviceTypeDMA:
+		return "DMA"
+	default:
+		return fmt.Sprintf("Unrecognized Device Type: %d", t)
+	}
+}
+
+type DeviceError uint64
+
+const (
+	DeviceErrorNone DeviceError = iota
+	DeviceErrorUnsupported
+	DeviceErrorReadOnly
+	DeviceErrorAlignment
+	DeviceErrorCapacity
+)
+
+// DeviceDescriptor describes a device, and is written to memory by DeviceStatus.
+type DeviceDescriptor struct {
+	Manufacturer    uint64
+	State           DeviceState
+	Type            DeviceType
+	HardwareVersion uint64
+	SoftwareVersion uint64
+	Capacity        uint64
+	Detail          uint64
+	Error           DeviceError
+}
+
+// Fields returns the fields of the descriptor, in the order written to memory.
+func (d DeviceDescriptor) Fields() [DeviceStatusFields]uint64 {
+	return [DeviceStatusFields]uint64{
+		d.Manufacturer,
+		uint64(d.State),
+		uint64(d.Type),
+		d.HardwareVersion,
+		d.SoftwareVersion,
+		d.Capacity,
+		d.Detail,
+		uint64(d.Error),
+	}
+}
+
+// NewDeviceDescriptor returns the descriptor with the given fields, in the order written to memory.
+func NewDeviceDescriptor(fields [DeviceStatusFields]uint64) DeviceDescriptor {
+	return DeviceDescriptor{
+		Manufacturer:    fields[DeviceStatusManufacturer],
+		State:           DeviceState(fields[DeviceStatusState]),
+		Type:            DeviceType(fields[DeviceStatusType]),
+		HardwareVersion: fields[DeviceStatusHardwareVersion],
+		SoftwareVersion: fields[DeviceStatusSoftwareVersion],
+		Capacity:        fields[DeviceStatusCapacity],
+		Detail:          fields[DeviceStatusDetail],
+		Error:           DeviceError(fields[DeviceStatusError]),
+	}
+}
+
+// Offsets of the 64bit fields of each entry of the device table, in units of DataSize.
+const (
+	DeviceEntryId           uint32 = iota
+	DeviceEntryType                // DeviceType
+	DeviceEntryControlBlock        // Address of the control block
+	DeviceEntryRoute               // Context interrupted by the signals of the device, or 0xffff if unrouted
+	DeviceEntryFields
+)
+
+// The device table is written to memory at boot, and lists the interrupt controller and every device.
+// The table is a 64bit magic number and a 64bit count of entries, followed by the entries.
+const (
+	// ASCII "FLAMEDEV"
+	DeviceTableMagic = 0x464c414d45444556
+
+	// Unit: Bytes
+	DeviceTableHeaderSize = 2 * DataSize
+	DeviceTableEntrySize  = 4 * DataSize
+
+	// Holds the address of the device table when the boot context is first signalled, the bootloader leaves it for the kernel
+	DeviceTableRegister = R30
+)
+
 type Device interface {
 	Clockable
 
@@ -0,0 +1,277 @@
+package emulator
+
+import (
+	"aletheiaware.com/flamego"
+	"encoding/binary"
+)
+
+const (
+	// Unit: Bits
+	GranuleBits = 6
+	// Unit: Bytes
+	GranuleSize = 1 << GranuleBits
+	// Unit: Instructions
+	BlockLimit = 64
+)
+
+// Emulator executes a single context functionally, without modelling the pipeline, caches, or timing of the virtual machine.
+// Straight-line runs of instructions are translated into blocks of Go closures, cached by address.
+// Stores into translated instructions invalidate their blocks.
+func NewEmulator(memory []byte) *Emulator {
+	e := &Emulator{
+		memory:        memory,
+		blocks:        make(map[uint64]*block),
+		granules:      make(map[uint64][]*block),
+		code:          make([]bool, (len(memory)+GranuleSize-1)/GranuleSize),
+		nextInterrupt: -1,
+		source:        flamego.InterruptSourceHost,
+		asleep:        true,
+	}
+	e.registers[flamego.R1] = 1
+	return e
+}
+
+type Emulator struct {
+	memory []byte
+	// R0 - R3 are never written, so they hold their read-only values
+	registers [flamego.RegisterCount]uint64
+
+	isInterrupted bool
+	nextInterrupt flamego.InterruptValue
+	signals       []int
+	source        int
+	asleep        bool
+	halted        bool
+	retired       uint64
+	epoch         uint64
+
+	blocks      map[uint64]*block
+	granules    map[uint64][]*block // Blocks containing instructions in each granule of memory
+	code        []bool              // Whether each granule of memory contains translated instructions
+	translated  uint64
+	invalidated uint64
+	stale       bool
+
+	// OnSignal is called when the context signals a device, or another context
+	OnSignal func(int)
+}
+
+// SetIdentifier sets the core and context identifiers read from R2 and R3.
+func (e *Emulator) SetIdentifier(core, context int) {
+	e.registers[flamego.RCoreIdentifier] = uint64(core)
+	e.registers[flamego.RContextIdentifier] = uint64(context)
+}
+
+func (e *Emulator) Memory() []byte {
+	return e.memory
+}
+
+// Epoch is the time, in nanoseconds, at which the emulator started.
+func (e *Emulator) Epoch() uint64 {
+	return e.epoch
+}
+
+func (e *Emulator) SetEpoch(epoch uint64) {
+	e.epoch = epoch
+}
+
+func (e *Emulator) IsAsleep() bool {
+	return e.asleep
+}
+
+func (e *Emulator) IsInterrupted() bool {
+	return e.isInterrupted
+}
+
+func (e *Emulator) HasHalted() bool {
+	return e.halted
+}
+
+func (e *Emulator) RetiredInstructions() uint64 {
+	return e.retired
+}
+
+// TranslatedBlocks returns the number of blocks translated, including those translated again after being invalidated.
+func (e *Emulator) TranslatedBlocks() uint64 {
+	return e.translated
+}
+
+// InvalidatedBlocks returns the number of blocks invalidated by stores into their instructions.
+func (e *Emulator) InvalidatedBlocks() uint64 {
+	return e.invalidated
+}
+
+// Signal interrupts the context on behalf of the source, waking it if asleep.
+func (e *Emulator) Signal(source int) {
+	e.signals = append(e.signals, source)
+}
+
+func (e *Emulator) IsSignalled() bool {
+	return len(e.signals) > 0
+}
+
+func (e *Emulator) Error(value flamego.InterruptValue) {
+	if e.isInterrupted {
+		panic("Double Interrupt")
+	}
+	e.nextInterrupt = value
+}
+
+func (e *Emulator) ReadRegister(register flamego.Register) uint64 {
+	if register > flamego.R31 {
+		e.Error(flamego.InterruptRegisterAccessError)
+		return 0
+	}
+	return e.registers[register]
+}
+
+// WriteRegister has the same semantics as the virtual machine; read-only registers cannot be written,
+// and privileged registers can only be written in an interrupt.
+func (e *Emulator) WriteRegister(register flamego.Register, value uint64) bool {
+	if register >= flamego.R16 && register <= flamego.R31 {
+		e.registers[register] = value
+		return true
+	}
+	return e.writeSpecial(register, value)
+}
+
+func (e *Emulator) writeSpecial(register flamego.Register, value uint64) bool {
+	switch {
+	case register > flamego.R31, register <= flamego.R3:
+		e.Error(flamego.InterruptRegisterAccessError)
+		return false
+	case !e.isInterrupted:
+		e.Error(flamego.InterruptRegisterAccessError)
+		return false
+	}
+	e.registers[register] = value
+	return true
+}
+
+// Run executes instructions until the context halts, sleeps, or has retired at least the given number of instructions.
+// Returns the number of instructions retired.
+func (e *Emulator) Run(limit uint64) uint64 {
+	start := e.retired
+	var previous *block
+	for !e.halted && e.retired-start < limit {
+		if !e.isInterrupted {
+			if e.nextInterrupt >= 0 {
+				e.interrupt(e.nextInterrupt)
+				previous = nil
+			} else if len(e.signals) > 0 {
+				e.source = e.signals[0]
+				e.signals = e.signals[1:]
+				e.asleep = false
+				e.interrupt(flamego.InterruptSignal)
+				previous = nil
+			}
+		}
+		if e.asleep {
+			break
+		}
+
+		pc := e.registers[flamego.RProgramCounter]
+		count := BlockLimit
+		if !e.isInterrupted {
+			pc += e.registers[flamego.RProgramStart]
+			end := e.registers[flamego.RProgramLimit]
+			if pc >= end {
+				e.Error(flamego.InterruptProgramAccessError)
+				continue
+			}
+			if remaining := (end - pc + flamego.InstructionSize - 1) / flamego.InstructionSize; remaining < uint64(count) {
+				// Instructions beyond the program limit are not executed
+				count = int(remaining)
+			}
+		}
+		if pc%flamego.InstructionSize != 0 || pc+flamego.InstructionSize > uint64(len(e.memory)) {
+			e.Error(flamego.InterruptProgramAccessError)
+			continue
+		}
+
+		b := previous.successor(pc)
+		if b == nil {
+			b = e.block(pc)
+			previous.link(b)
+		}
+		if count > len(b.operations) {
+			count = len(b.operations)
+		}
+		n := 0
+		for n < count {
+			op := b.operations[n]
+			n++
+			if !op(e) {
+				break
+			}
+		}
+		e.retired += uint64(n)
+		previous = b
+		if !b.valid {
+			// Block invalidated by its own store
+			previous = nil
+		}
+	}
+	return e.retired - start
+}
+
+// interrupt enters the interrupt service routine in the interrupt vector table for the given value,
+// recording the program counter and value in R14 and R15.
+func (e *Emulator) interrupt(value flamego.InterruptValue) {
+	e.nextInterrupt = -1
+	e.isInterrupted = true
+	e.registers[flamego.RInterruptReturn] = e.registers[flamego.RProgramCounter]
+	e.registers[flamego.RInterruptValue] = uint64(value)
+	e.registers[flamego.RProgramCounter] = e.registers[flamego.RInterruptVectorTable] + uint64(value)
+}
+
+func (e *Emulator) read(address uint64) (uint64, bool) {
+	if address+flamego.DataSize > uint64(len(e.memory)) || address+flamego.DataSize < address {
+		e.Error(flamego.InterruptMemoryAccessError)
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(e.memory[address:]), true
+}
+
+func (e *Emulator) write(address, value uint64) bool {
+	if address+flamego.DataSize > uint64(len(e.memory)) || address+flamego.DataSize < address {
+		e.Error(flamego.InterruptMemoryAccessError)
+		return false
+	}
+	binary.BigEndian.PutUint64(e.memory[address:], value)
+	first, last := address>>GranuleBits, (address+flamego.DataSize-1)>>GranuleBits
+	if e.code[first] || e.code[last] {
+		// Store into translated instructions
+		if e.invalidate(first, last) {
+			e.stale = true
+		}
+	}
+	return true
+}
+
+// resume returns false if the block being executed may have been invalidated, so the next instruction is translated again.
+func (e *Emulator) resume() bool {
+	if e.stale {
+		e.stale = false
+		return false
+	}
+	return true
+}
+
+// invalidate discards the blocks containing instructions in the given granules, returning true if any were discarded.
+func (e *Emulator) invalidate(first, last uint64) bool {
+	invalidated := false
+	for g := first; g <= last; g++ {
+		for _, b := range e.granules[g] {
+			if b.valid {
+				b.valid = false
+				delete(e.blocks, b.address)
+				e.invalidated++
+				invalidated = true
+			}
+		}
+		delete(e.granules, g)
+		e.code[g] = false
+	}
+	return invalidated
+}
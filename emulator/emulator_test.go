@@ -0,0 +1,144 @@
+package emulator_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/assembler"
+	"aletheiaware.com/flamego/emulator"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assemble(t testing.TB, source io.Reader) []byte {
+	t.Helper()
+	a := assembler.NewAssembler()
+	_, err := a.ReadFrom(source)
+	assert.NoError(t, err)
+	var program bytes.Buffer
+	_, err = a.WriteTo(&program)
+	assert.NoError(t, err)
+	return program.Bytes()
+}
+
+func newEmulator(t testing.TB, program []byte) *emulator.Emulator {
+	t.Helper()
+	memory := make([]byte, 0x10000)
+	copy(memory, program)
+	e := emulator.NewEmulator(memory)
+	e.Signal(flamego.InterruptSourceHost)
+	return e
+}
+
+func TestEmulator_Samples(t *testing.T) {
+	for name, tt := range map[string]map[flamego.Register]uint64{
+		"add": {
+			flamego.R16: 17,
+			flamego.R17: 33,
+			flamego.R18: 50,
+		},
+		"call": {
+			flamego.R16: 4,
+			flamego.R17: 1,
+			flamego.R18: 2,
+			flamego.R19: 3,
+			flamego.R20: 4,
+		},
+		"loop": {
+			flamego.R16: 0,
+		},
+		"saverestore": {
+			flamego.R16: 5,
+			flamego.R17: 6,
+		},
+		"storeandflush": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("..", "assembler", "samples", name+".fas"))
+			assert.NoError(t, err)
+			defer f.Close()
+			e := newEmulator(t, assemble(t, f))
+			e.Run(1000)
+			assert.True(t, e.HasHalted())
+			for r, v := range tt {
+				assert.Equal(t, v, e.ReadRegister(r), r.String())
+			}
+		})
+	}
+}
+
+func TestEmulator_SelfModifyingCode(t *testing.T) {
+	// Overwrites the first two instructions of the loop body, which has already been translated,
+	// so the second iteration loads 7 instead of 3.
+	e := newEmulator(t, assemble(t, strings.NewReader(`
+loadc #Patch r20
+load r20 0 r21
+loadc #Body r20
+loadc 2 r16
+#Body
+loadc 3 r17
+noop
+subtract r16 r1 r16
+jez r16 #End
+store r20 0 r21
+jump #Body
+#End
+halt
+
+align 0x40
+#Patch
+loadc 7 r17
+noop
+`)))
+	e.Run(1000)
+	assert.True(t, e.HasHalted())
+	assert.Equal(t, uint64(7), e.ReadRegister(flamego.R17))
+	// Both the first block, which runs into the loop body, and the loop body block are invalidated
+	assert.Equal(t, uint64(2), e.InvalidatedBlocks())
+}
+
+func TestEmulator_PrivilegedRegister(t *testing.T) {
+	// Program runs outside of an interrupt, so writing the Stack Pointer raises an error,
+	// and the error handler records the value and halts.
+	e := newEmulator(t, assemble(t, strings.NewReader(`
+loadc #Vectors rIVT
+loadc #Program rPS
+loadc #ProgramEnd rPL
+uninterrupt r0
+
+align 0x100
+#Vectors
+noop
+#RegisterAccessError
+copy rIV r20
+halt
+
+#Program
+loadc 0x10 rSP
+halt
+#ProgramEnd
+`)))
+	// Run the setup, which returns from the interrupt into the program
+	e.Run(5)
+	assert.False(t, e.IsInterrupted())
+	e.Run(1000)
+	assert.True(t, e.HasHalted())
+	assert.Equal(t, uint64(flamego.InterruptRegisterAccessError), e.ReadRegister(flamego.R20))
+	assert.Equal(t, uint64(0), e.ReadRegister(flamego.RStackPointer))
+}
+
+func BenchmarkEmulator(b *testing.B) {
+	e := newEmulator(b, assemble(b, strings.NewReader(`
+#Loop
+add r16 r1 r16
+xor r17 r16 r17
+multiply r16 r16 r18
+jump #Loop
+`)))
+	b.ResetTimer()
+	e.Run(uint64(b.N))
+	b.ReportMetric(float64(e.RetiredInstructions())/b.Elapsed().Seconds(), "instructions/s")
+}
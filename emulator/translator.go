@@ -0,0 +1,460 @@
+package emulator
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/isa"
+	"encoding/binary"
+)
+
+// operation executes a translated instruction, returning false if the rest of the block must not be executed,
+// because the instruction transferred control, raised an error, or invalidated translated instructions.
+type operation func(*Emulator) bool
+
+type block struct {
+	address    uint64
+	operations []operation
+	valid      bool
+	successors [2]*block // Blocks most recently executed after this block
+}
+
+// successor returns the valid block at the given address most recently executed after this block.
+func (b *block) successor(address uint64) *block {
+	if b == nil {
+		return nil
+	}
+	for _, s := range b.successors {
+		if s != nil && s.address == address && s.valid {
+			return s
+		}
+	}
+	return nil
+}
+
+// link records the block executed after this block, replacing the least recent successor.
+func (b *block) link(s *block) {
+	if b == nil {
+		return
+	}
+	b.successors[1] = b.successors[0]
+	b.successors[0] = s
+}
+
+// block returns the translated block starting at the given address, translating it if necessary.
+func (e *Emulator) block(address uint64) *block {
+	if b, ok := e.blocks[address]; ok {
+		return b
+	}
+	b := e.translate(address)
+	e.blocks[address] = b
+	e.translated++
+	// Record the granules containing the block so stores into them invalidate it
+	limit := address + uint64(len(b.operations))*flamego.InstructionSize
+	for g := address >> GranuleBits; g <= (limit-1)>>GranuleBits; g++ {
+		e.granules[g] = append(e.granules[g], b)
+		e.code[g] = true
+	}
+	return b
+}
+
+// translate decodes instructions from the given address until an instruction which may transfer control, or the block limit.
+func (e *Emulator) translate(address uint64) *block {
+	b := &block{
+		address: address,
+		valid:   true,
+	}
+	for a := address; len(b.operations) < BlockLimit && a+flamego.InstructionSize <= uint64(len(e.memory)); a += flamego.InstructionSize {
+		opcode := binary.BigEndian.Uint32(e.memory[a:])
+		instruction, ok := decode(opcode)
+		if !ok {
+			if len(b.operations) == 0 {
+				// Unrecognized opcodes fail when executed, as they do in the virtual machine
+				b.operations = append(b.operations, func(e *Emulator) bool {
+					isa.Decode(opcode)
+					return false
+				})
+			}
+			break
+		}
+		op, last := translateInstruction(instruction, len(b.operations))
+		b.operations = append(b.operations, op)
+		if last {
+			break
+		}
+	}
+	return b
+}
+
+func decode(opcode uint32) (instruction flamego.Instruction, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	return isa.Decode(opcode), true
+}
+
+// translateInstruction returns the operation for the instruction at the given index of its block,
+// and whether the instruction ends the block.
+func translateInstruction(instruction flamego.Instruction, index int) (operation, bool) {
+	switch i := instruction.(type) {
+	case *isa.LoadC:
+		c, d := uint64(i.Constant), i.DestinationRegister
+		return func(e *Emulator) bool {
+			ok := e.WriteRegister(d, c)
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}, false
+	case *isa.Jump:
+		return translateJump(i), true
+	case *isa.Load:
+		a, o, d := i.AddressRegister, uint64(i.Offset), i.DestinationRegister
+		return func(e *Emulator) bool {
+			v, ok := e.read(e.registers[a] + o)
+			if !ok {
+				return false
+			}
+			ok = e.WriteRegister(d, v)
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}, false
+	case *isa.Store:
+		a, o, r := i.AddressRegister, uint64(i.Offset), i.SourceRegister
+		return func(e *Emulator) bool {
+			if !e.write(e.registers[a]+o, e.registers[r]) {
+				return false
+			}
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return e.resume()
+		}, false
+	case *isa.Clear, *isa.Flush:
+		// No caches to clear or flush
+		return increment, false
+	case *isa.Not:
+		s, d := i.SourceRegister, i.DestinationRegister
+		return func(e *Emulator) bool {
+			ok := e.WriteRegister(d, ^e.registers[s])
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}, false
+	case *isa.And:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a & b }), false
+	case *isa.Or:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a | b }), false
+	case *isa.Xor:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a ^ b }), false
+	case *isa.LeftShift:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a << b }), false
+	case *isa.RightShift:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a >> b }), false
+	case *isa.Add:
+		s1, s2, d := i.Source1Register, i.Source2Register, i.DestinationRegister
+		return func(e *Emulator) bool {
+			ok := e.WriteRegister(d, e.registers[s1]+e.registers[s2])
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}, false
+	case *isa.Subtract:
+		s1, s2, d := i.Source1Register, i.Source2Register, i.DestinationRegister
+		return func(e *Emulator) bool {
+			ok := e.WriteRegister(d, e.registers[s1]-e.registers[s2])
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}, false
+	case *isa.Multiply:
+		return arithmetic(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a * b }), false
+	case *isa.Divide:
+		return division(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a / b }), false
+	case *isa.Modulo:
+		return division(i.Source1Register, i.Source2Register, i.DestinationRegister, func(a, b uint64) uint64 { return a % b }), false
+	case *isa.Push:
+		return translatePush(i.Mask), false
+	case *isa.Pop:
+		return translatePop(i.Mask), false
+	case *isa.Call:
+		return translateCall(i.AddressRegister), true
+	case *isa.Return:
+		return translateReturn(), true
+	case *isa.Halt:
+		return privileged(func(e *Emulator) bool {
+			e.halted = true
+			return false
+		}), true
+	case *isa.Noop:
+		return increment, false
+	case *isa.Sleep:
+		return privileged(func(e *Emulator) bool {
+			e.isInterrupted = false
+			e.asleep = true
+			return false
+		}), true
+	case *isa.Signal:
+		r := i.DeviceIdRegister
+		return privileged(func(e *Emulator) bool {
+			if f := e.OnSignal; f != nil {
+				f(int(e.registers[r]))
+			}
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return true
+		}), false
+	case *isa.Lock:
+		// A single context always acquires, and releases, a lock immediately, and unknown locks are ignored
+		return translateLock(), false
+	case *isa.Unlock:
+		return translateLock(), false
+	case *isa.Interrupt:
+		v := i.Value
+		return func(e *Emulator) bool {
+			e.interrupt(v)
+			return false
+		}, true
+	case *isa.Uninterrupt:
+		r := i.AddressRegister
+		return privileged(func(e *Emulator) bool {
+			e.registers[flamego.RProgramCounter] = e.registers[r]
+			e.isInterrupted = false
+			return false
+		}), true
+	case *isa.Timestamp:
+		return translateTimestamp(i.Counter, i.DestinationRegister, index), false
+	case *isa.Acknowledge:
+		d := i.DestinationRegister
+		return privileged(func(e *Emulator) bool {
+			source := e.source
+			e.source = flamego.InterruptSourceHost
+			ok := e.WriteRegister(d, uint64(source))
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return ok
+		}), false
+	case *isa.Send, *isa.Receive, *isa.Spawn:
+		// Messages and spawning require other contexts
+		return unsupported, true
+	case *isa.Exit:
+		return func(e *Emulator) bool {
+			e.isInterrupted = false
+			e.asleep = true
+			return false
+		}, true
+	case *isa.Save:
+		return translateSave(i.AddressRegister), false
+	case *isa.Restore:
+		return translateRestore(i.AddressRegister), false
+	}
+	return unsupported, true
+}
+
+func increment(e *Emulator) bool {
+	e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+	return true
+}
+
+func unsupported(e *Emulator) bool {
+	e.Error(flamego.InterruptUnsupportedOperationError)
+	return false
+}
+
+// privileged returns an operation which raises an error outside of an interrupt.
+func privileged(op operation) operation {
+	return func(e *Emulator) bool {
+		if !e.isInterrupted {
+			e.Error(flamego.InterruptUnsupportedOperationError)
+			return false
+		}
+		return op(e)
+	}
+}
+
+func arithmetic(s1, s2, d flamego.Register, f func(uint64, uint64) uint64) operation {
+	return func(e *Emulator) bool {
+		ok := e.WriteRegister(d, f(e.registers[s1], e.registers[s2]))
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return ok
+	}
+}
+
+func division(s1, s2, d flamego.Register, f func(uint64, uint64) uint64) operation {
+	return func(e *Emulator) bool {
+		b := e.registers[s2]
+		if b == 0 {
+			e.Error(flamego.InterruptArithmeticError)
+			// Destination is still written, as in the virtual machine
+			e.WriteRegister(d, 0)
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+			return false
+		}
+		ok := e.WriteRegister(d, f(e.registers[s1], b))
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return ok
+	}
+}
+
+func translateJump(i *isa.Jump) operation {
+	c, offset := i.ConditionRegister, uint64(i.Offset)
+	if i.Direction == isa.JumpBackward {
+		offset = -offset
+	}
+	var condition func(uint64) bool
+	switch i.ConditionCode {
+	case isa.JumpEZ:
+		condition = func(v uint64) bool { return v == 0 }
+	case isa.JumpNZ:
+		condition = func(v uint64) bool { return v != 0 }
+	case isa.JumpLE:
+		condition = func(v uint64) bool { return int64(v) <= 0 }
+	case isa.JumpLZ:
+		condition = func(v uint64) bool { return int64(v) < 0 }
+	}
+	return func(e *Emulator) bool {
+		if condition(e.registers[c]) {
+			e.registers[flamego.RProgramCounter] += offset
+		} else {
+			e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		}
+		return false
+	}
+}
+
+func translatePush(mask uint16) operation {
+	return func(e *Emulator) bool {
+		// Start with r16 (MSB), interate up to r31 (LSB)
+		for index := 15; index >= 0; index-- {
+			if mask&(1<<index) == 0 {
+				continue
+			}
+			sp := e.registers[flamego.RStackPointer]
+			if sp >= e.registers[flamego.RStackLimit] {
+				e.Error(flamego.InterruptStackOverflowError)
+				return false
+			}
+			if !e.write(sp, e.registers[flamego.R31-flamego.Register(index)]) {
+				return false
+			}
+			if !e.WriteRegister(flamego.RStackPointer, sp+flamego.DataSize) {
+				return false
+			}
+		}
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return e.resume()
+	}
+}
+
+func translatePop(mask uint16) operation {
+	return func(e *Emulator) bool {
+		// Start with r31 (LSB), interate down to r16 (MSB)
+		for index := 0; index < 16; index++ {
+			if mask&(1<<index) == 0 {
+				continue
+			}
+			sp := e.registers[flamego.RStackPointer] - flamego.DataSize
+			if sp < e.registers[flamego.RStackStart] {
+				e.Error(flamego.InterruptStackUnderflowError)
+				return false
+			}
+			v, ok := e.read(sp)
+			if !ok {
+				return false
+			}
+			if !e.WriteRegister(flamego.RStackPointer, sp) || !e.WriteRegister(flamego.R31-flamego.Register(index), v) {
+				return false
+			}
+		}
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return true
+	}
+}
+
+func translateCall(r flamego.Register) operation {
+	return func(e *Emulator) bool {
+		sp := e.registers[flamego.RStackPointer]
+		if sp >= e.registers[flamego.RStackLimit] {
+			e.Error(flamego.InterruptStackOverflowError)
+			return false
+		}
+		address := e.registers[r]
+		// Push address of next instruction
+		if !e.write(sp, e.registers[flamego.RProgramCounter]+flamego.InstructionSize) {
+			return false
+		}
+		if e.WriteRegister(flamego.RStackPointer, sp+flamego.DataSize) {
+			e.WriteRegister(flamego.RProgramCounter, address)
+		}
+		e.resume()
+		return false
+	}
+}
+
+func translateReturn() operation {
+	return func(e *Emulator) bool {
+		sp := e.registers[flamego.RStackPointer] - flamego.DataSize
+		if sp < e.registers[flamego.RStackStart] {
+			e.Error(flamego.InterruptStackUnderflowError)
+			return false
+		}
+		address, ok := e.read(sp)
+		if !ok {
+			return false
+		}
+		if e.WriteRegister(flamego.RStackPointer, sp) {
+			e.WriteRegister(flamego.RProgramCounter, address)
+		}
+		return false
+	}
+}
+
+func translateLock() operation {
+	return privileged(func(e *Emulator) bool {
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return true
+	})
+}
+
+func translateTimestamp(c flamego.Counter, d flamego.Register, index int) operation {
+	return func(e *Emulator) bool {
+		// Each instruction takes one cycle, instructions before this one in the block have retired
+		retired := e.retired + uint64(index)
+		var v uint64
+		switch c {
+		case flamego.CounterCycle, flamego.CounterInstruction:
+			v = retired
+		case flamego.CounterTime:
+			v = e.epoch + retired*flamego.ClockPeriod
+		default:
+			e.Error(flamego.InterruptUnsupportedOperationError)
+			return false
+		}
+		ok := e.WriteRegister(d, v)
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return ok
+	}
+}
+
+func translateSave(r flamego.Register) operation {
+	return privileged(func(e *Emulator) bool {
+		address := e.registers[r]
+		for index := 0; index < flamego.ContextStateLength; index++ {
+			if !e.write(address+uint64(index)*flamego.DataSize, e.registers[flamego.R4+flamego.Register(index)]) {
+				return false
+			}
+		}
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return e.resume()
+	})
+}
+
+func translateRestore(r flamego.Register) operation {
+	return privileged(func(e *Emulator) bool {
+		// Load Block Address once, as the Address Register may be restored
+		address := e.registers[r]
+		for index := 0; index < flamego.ContextStateLength; index++ {
+			v, ok := e.read(address + uint64(index)*flamego.DataSize)
+			if !ok {
+				return false
+			}
+			if register := flamego.R4 + flamego.Register(index); register != flamego.RProgramCounter {
+				// The Program Counter is not restored as the interrupt continues
+				e.registers[register] = v
+			}
+		}
+		e.registers[flamego.RProgramCounter] += flamego.InstructionSize
+		return true
+	})
+}
@@ -14,3 +14,18 @@ type Instruction interface {
 	Store(Context, uint64, uint64)
 	Retire(Context) bool
 }
+
+// InstructionState holds the transient state of an instruction as it progresses through the pipeline.
+// The state is kept by the context executing the instruction, so decoded instructions are immutable and can be shared.
+// The state is cleared when the context decodes the next instruction.
+type InstructionState struct {
+	Success   bool
+	Issued    bool
+	Loaded    bool
+	Index     int
+	Mask      uint16
+	Address   uint64
+	Issues    [CacheLevels]bool // Requests issued to each level of the cache hierarchy
+	Completes [CacheLevels]bool // Requests completed by each level of the cache hierarchy
+	Registers [SpawnDescriptorLength]uint64
+}
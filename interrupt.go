@@ -23,3 +23,35 @@ const InterruptCount = 9
 func (i InterruptValue) String() string {
 	return fmt.Sprintf("Interrupt 0x%04x", uint16(i))
 }
+
+const (
+	// Identifies the host as the source of an interrupt
+	InterruptSourceHost = -1
+	// Identifies the interrupt controller as a signal target or interrupt source
+	InterruptControllerId = 0xffff
+)
+
+const (
+	InterruptControlBlockAddress = DeviceControlBlockAddress - DeviceControlBlockSize
+)
+
+type InterruptController interface {
+	Device
+
+	// Route directs interrupts from the given source to the given context, or to the target requested by the source if the context is negative
+	Route(int, int)
+	// Mask holds, or releases, interrupts from the given source
+	Mask(int, bool)
+	IsMasked(int) bool
+	// SetPriority orders interrupts from the given source, higher priorities are claimed first
+	SetPriority(int, uint8)
+
+	// Raise an interrupt from the given source to the given context
+	Raise(int, int)
+	// IsPending reports whether the given context has any pending interrupts
+	IsPending(int) bool
+	// Claim removes the highest priority pending interrupt of the given context and returns its source, and whether more interrupts are pending
+	Claim(int) (int, bool)
+	// Acknowledge returns the source of the interrupt being serviced by the given context, or InterruptSourceHost if there is none
+	Acknowledge(int) int
+}
@@ -0,0 +1,60 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Acknowledge struct {
+	DestinationRegister flamego.Register
+}
+
+func NewAcknowledge(r flamego.Register) *Acknowledge {
+	return &Acknowledge{
+		DestinationRegister: r,
+	}
+}
+
+func (i *Acknowledge) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Do Nothing
+	return 0, 0, 0, 0
+}
+
+func (i *Acknowledge) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Acknowledge only allowed in an interrupt
+		x.Error(flamego.InterruptUnsupportedOperationError)
+		s.Success = false
+		return 0, 0
+	}
+	// Acknowledge Interrupt Source
+	return uint64(x.Core().Processor().InterruptController().Acknowledge(flamego.ContextIdentifier(x))), 0
+}
+
+func (i *Acknowledge) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	// Pass Through
+	return a, 0
+}
+
+func (i *Acknowledge) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if s.Success {
+		// Write Destination Register
+		x.WriteRegister(i.DestinationRegister, a)
+	}
+}
+
+func (i *Acknowledge) Retire(x flamego.Context) bool {
+	s := x.State()
+	if s.Success {
+		x.IncrementProgramCounter()
+	}
+	return true
+}
+
+func (i *Acknowledge) String() string {
+	return fmt.Sprintf("acknowledge %s", i.DestinationRegister)
+}
@@ -0,0 +1,21 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+)
+
+// readBus returns the big endian value on the bus.
+func readBus(bus flamego.Bus) uint64 {
+	var value uint64
+	for i := 0; i < flamego.DataSize; i++ {
+		value = value<<8 | uint64(bus.Read(i))
+	}
+	return value
+}
+
+// writeBus puts the value on the bus in big endian order.
+func writeBus(bus flamego.Bus, value uint64) {
+	for i := 0; i < flamego.DataSize; i++ {
+		bus.Write(i, byte(value>>(56-8*i)))
+	}
+}
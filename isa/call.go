@@ -2,14 +2,11 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
-	"encoding/binary"
 	"fmt"
 )
 
 type Call struct {
 	AddressRegister flamego.Register
-	success         bool
-	issued          bool
 }
 
 func NewCall(a flamego.Register) *Call {
@@ -19,7 +16,8 @@ func NewCall(a flamego.Register) *Call {
 }
 
 func (i *Call) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Stack Pointer Register
 	a := x.ReadRegister(flamego.RStackPointer)
 	// Load Stack Limit Register
@@ -32,40 +30,38 @@ func (i *Call) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Call) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	// Calculate address of next instruction
 	c += flamego.InstructionSize
 	if a >= b {
 		x.Error(flamego.InterruptStackOverflowError)
-		i.success = false
-	} else if !i.issued {
+		s.Success = false
+	} else if !s.Issued {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 		// Copy Data to Bus
-		buffer := make([]byte, 8)
-		binary.BigEndian.PutUint64(buffer, c)
-		for i := 0; i < 8; i++ {
-			l1d.Bus().Write(i, buffer[i])
-		}
+		writeBus(l1d.Bus(), c)
 		// Issue Write Request
 		l1d.Write(a)
-		i.issued = true
+		s.Issued = true
 	}
 	return a, d
 }
 
 func (i *Call) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
 	l1d := x.DataCache()
 	if l1d.IsBusy() {
-		i.success = false
+		s.Success = false
 	} else if !l1d.IsSuccessful() {
-		i.success = false
-		i.issued = false // Reissue Request
+		s.Success = false
+		s.Issued = false // Reissue Request
 		l1d.Free()       // Free Cache
 	} else {
 		l1d.Free() // Free Cache
@@ -75,7 +71,8 @@ func (i *Call) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
 }
 
 func (i *Call) Store(x flamego.Context, a, b uint64) {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		// Update Stack Pointer
 		p := a + flamego.DataSize
 		x.WriteRegister(flamego.RStackPointer, p)
@@ -85,7 +82,8 @@ func (i *Call) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Call) Retire(x flamego.Context) bool {
-	return i.success
+	s := x.State()
+	return s.Success
 }
 
 func (i *Call) String() string {
@@ -8,15 +8,6 @@ import (
 type Clear struct {
 	AddressRegister flamego.Register
 	Offset          uint32
-	success         bool
-	issuedL1I       bool
-	issuedL1D       bool
-	issuedL2        bool
-	issuedL3        bool
-	clearedL1I      bool
-	clearedL1D      bool
-	clearedL2       bool
-	clearedL3       bool
 }
 
 func NewClear(a flamego.Register, o uint32) *Clear {
@@ -27,7 +18,8 @@ func NewClear(a flamego.Register, o uint32) *Clear {
 }
 
 func (i *Clear) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Base Register
 	a := x.ReadRegister(i.AddressRegister)
 	// Load Offset
@@ -36,101 +28,103 @@ func (i *Clear) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Clear) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	address := a + b
-	if !i.issuedL1I {
+	if !s.Issues[flamego.CacheLevelL1I] {
 		l1i := x.InstructionCache()
 		if l1i.IsBusy() || !l1i.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Clear Request
 		l1i.Clear(address)
-		i.issuedL1I = true
-	} else if !i.issuedL1D {
+		s.Issues[flamego.CacheLevelL1I] = true
+	} else if !s.Issues[flamego.CacheLevelL1D] {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Clear Request
 		l1d.Clear(address)
-		i.issuedL1D = true
-	} else if !i.issuedL2 {
+		s.Issues[flamego.CacheLevelL1D] = true
+	} else if !s.Issues[flamego.CacheLevelL2] {
 		l2 := x.Core().Cache()
 		if l2.IsBusy() || !l2.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Clear Request
 		l2.Clear(address)
-		i.issuedL2 = true
-	} else if !i.issuedL3 {
+		s.Issues[flamego.CacheLevelL2] = true
+	} else if !s.Issues[flamego.CacheLevelL3] {
 		l3 := x.Core().Processor().Cache()
 		if l3.IsBusy() || !l3.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Clear Request
 		l3.Clear(address)
-		i.issuedL3 = true
+		s.Issues[flamego.CacheLevelL3] = true
 	}
 	return 0, 0
 }
 
 func (i *Clear) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
-	if !i.clearedL1I {
+	if !s.Completes[flamego.CacheLevelL1I] {
 		l1i := x.InstructionCache()
 		if l1i.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l1i.IsSuccessful() {
-			i.success = false
-			i.issuedL1I = false // Reissue Request
-			l1i.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL1I] = false // Reissue Request
+			l1i.Free()                              // Free Cache
 		} else {
-			i.clearedL1I = true
+			s.Completes[flamego.CacheLevelL1I] = true
 			l1i.Free() // Free Cache
 		}
-	} else if !i.clearedL1D {
+	} else if !s.Completes[flamego.CacheLevelL1D] {
 		l1d := x.DataCache()
 		if l1d.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l1d.IsSuccessful() {
-			i.success = false
-			i.issuedL1D = false // Reissue Request
-			l1d.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL1D] = false // Reissue Request
+			l1d.Free()                              // Free Cache
 		} else {
-			i.clearedL1D = true
+			s.Completes[flamego.CacheLevelL1D] = true
 			l1d.Free() // Free Cache
 		}
-	} else if !i.clearedL2 {
+	} else if !s.Completes[flamego.CacheLevelL2] {
 		l2 := x.Core().Cache()
 		if l2.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l2.IsSuccessful() {
-			i.success = false
-			i.issuedL2 = false // Reissue Request
-			l2.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL2] = false // Reissue Request
+			l2.Free()                              // Free Cache
 		} else {
-			i.clearedL2 = true
+			s.Completes[flamego.CacheLevelL2] = true
 			l2.Free() // Free Cache
 		}
-	} else if !i.clearedL3 {
+	} else if !s.Completes[flamego.CacheLevelL3] {
 		l3 := x.Core().Processor().Cache()
 		if l3.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l3.IsSuccessful() {
-			i.success = false
-			i.issuedL3 = false // Reissue Request
-			l3.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL3] = false // Reissue Request
+			l3.Free()                              // Free Cache
 		} else {
-			i.clearedL3 = true
+			s.Completes[flamego.CacheLevelL3] = true
 			l3.Free() // Free Cache
 		}
 	}
@@ -142,7 +136,8 @@ func (i *Clear) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Clear) Retire(x flamego.Context) bool {
-	if i.success && i.clearedL1I && i.clearedL1D && i.clearedL2 && i.clearedL3 {
+	s := x.State()
+	if s.Success && s.Completes[flamego.CacheLevelL1I] && s.Completes[flamego.CacheLevelL1D] && s.Completes[flamego.CacheLevelL2] && s.Completes[flamego.CacheLevelL3] {
 		x.IncrementProgramCounter()
 		return true
 	}
@@ -0,0 +1,45 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+)
+
+const (
+	// Unit: Bits
+	DecodeCacheBits = 8
+	// Unit: Instructions
+	DecodeCacheSize = 1 << DecodeCacheBits
+)
+
+// DecodeCache is a direct mapped cache of decoded instructions indexed by opcode, so instructions executed repeatedly are only decoded, and allocated, once.
+// Decoded instructions are immutable as their transient state is held by the context executing them, so they can be shared between contexts.
+// A DecodeCache is not safe for concurrent use.
+type DecodeCache struct {
+	opcodes      [DecodeCacheSize]uint32
+	instructions [DecodeCacheSize]flamego.Instruction
+	hits         uint64
+	misses       uint64
+}
+
+// Decode returns the cached instruction for the opcode, decoding the opcode on a miss.
+func (c *DecodeCache) Decode(opcode uint32) flamego.Instruction {
+	// Fibonacci hash to spread similar opcodes across the cache
+	index := (opcode * 2654435769) >> (32 - DecodeCacheBits)
+	if i := c.instructions[index]; i != nil && c.opcodes[index] == opcode {
+		c.hits++
+		return i
+	}
+	c.misses++
+	i := Decode(opcode)
+	c.opcodes[index] = opcode
+	c.instructions[index] = i
+	return i
+}
+
+func (c *DecodeCache) Hits() uint64 {
+	return c.hits
+}
+
+func (c *DecodeCache) Misses() uint64 {
+	return c.misses
+}
@@ -0,0 +1,28 @@
+package isa_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/isa"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeCache(t *testing.T) {
+	var cache isa.DecodeCache
+	add := isa.Encode(isa.NewAdd(flamego.R16, flamego.R17, flamego.R18))
+	halt := isa.Encode(isa.NewHalt())
+
+	i1 := cache.Decode(add)
+	assert.Equal(t, "add r16 r17 r18", i1.String())
+	assert.Equal(t, uint64(0), cache.Hits())
+	assert.Equal(t, uint64(1), cache.Misses())
+
+	// Decoded instruction is shared
+	i2 := cache.Decode(add)
+	assert.Same(t, i1, i2)
+	assert.Equal(t, uint64(1), cache.Hits())
+
+	i3 := cache.Decode(halt)
+	assert.Equal(t, "halt", i3.String())
+	assert.Equal(t, uint64(2), cache.Misses())
+}
@@ -8,6 +8,7 @@ import (
 const (
 	Width1Bit  = 0x1
 	Width2Bit  = 0x3
+	Width3Bit  = 0x7
 	Width4Bit  = 0xF
 	Width5Bit  = 0x1F
 	Width8Bit  = 0xFF
@@ -80,13 +81,29 @@ func Encode(instruction flamego.Instruction) uint32 {
 	case *Signal:
 		return (1 << 24) | (3 << 20) | uint32(i.DeviceIdRegister)
 	case *Lock:
-		return (1 << 24) | (4 << 20)
+		return (1 << 24) | (4 << 20) | uint32(i.LockRegister)
 	case *Unlock:
-		return (1 << 24) | (5 << 20)
+		return (1 << 24) | (5 << 20) | uint32(i.LockRegister)
 	case *Interrupt:
 		return (1 << 24) | (6 << 20) | (uint32(i.Value) & Width8Bit)
 	case *Uninterrupt:
 		return (1 << 24) | (7 << 20) | uint32(i.AddressRegister)
+	case *Timestamp:
+		return (1 << 24) | (8 << 20) | ((uint32(i.Counter) & Width3Bit) << 5) | uint32(i.DestinationRegister)
+	case *Acknowledge:
+		return (1 << 24) | (9 << 20) | uint32(i.DestinationRegister)
+	case *Send:
+		return (1 << 24) | (10 << 20) | (uint32(i.ContextRegister) << 10) | (uint32(i.MessageRegister) << 5) | uint32(i.DestinationRegister)
+	case *Receive:
+		return (1 << 24) | (11 << 20) | uint32(i.DestinationRegister)
+	case *Spawn:
+		return (1 << 24) | (12 << 20) | (uint32(i.ContextRegister) << 10) | (uint32(i.DescriptorRegister) << 5) | uint32(i.DestinationRegister)
+	case *Exit:
+		return (1 << 24) | (13 << 20)
+	case *Save:
+		return (1 << 24) | (14 << 20) | uint32(i.AddressRegister)
+	case *Restore:
+		return (1 << 24) | (15 << 20) | uint32(i.AddressRegister)
 	}
 	panic(fmt.Sprintf("Unrecognize Instruction: %+v\n", instruction))
 	return 0
@@ -170,13 +187,29 @@ func Decode(opcode uint32) flamego.Instruction {
 		case 3:
 			return NewSignal(flamego.Register(opcode & WidthRegister))
 		case 4:
-			return NewLock()
+			return NewLock(flamego.Register(opcode & WidthRegister))
 		case 5:
-			return NewUnlock()
+			return NewUnlock(flamego.Register(opcode & WidthRegister))
 		case 6:
 			return NewInterrupt(flamego.InterruptValue(opcode & Width8Bit))
 		case 7:
 			return NewUninterrupt(flamego.Register(opcode & WidthRegister))
+		case 8:
+			return NewTimestamp(flamego.Counter((opcode>>5)&Width3Bit), flamego.Register(opcode&WidthRegister))
+		case 9:
+			return NewAcknowledge(flamego.Register(opcode & WidthRegister))
+		case 10:
+			return NewSend(flamego.Register((opcode>>10)&WidthRegister), flamego.Register((opcode>>5)&WidthRegister), flamego.Register(opcode&WidthRegister))
+		case 11:
+			return NewReceive(flamego.Register(opcode & WidthRegister))
+		case 12:
+			return NewSpawn(flamego.Register((opcode>>10)&WidthRegister), flamego.Register((opcode>>5)&WidthRegister), flamego.Register(opcode&WidthRegister))
+		case 13:
+			return NewExit()
+		case 14:
+			return NewSave(flamego.Register(opcode & WidthRegister))
+		case 15:
+			return NewRestore(flamego.Register(opcode & WidthRegister))
 		}
 	}
 	panic(fmt.Sprintf("Unrecognized Opcode: 0x%016x %032b\n", uint32(opcode), uint32(opcode)))
@@ -28,12 +28,12 @@ func TestEncoding(t *testing.T) {
 			assert.Equal(t, "00000001001100000000000000011111", fmt.Sprintf("%032b", opcode))
 		})
 		t.Run("Lock", func(t *testing.T) {
-			opcode := isa.Encode(isa.NewLock())
-			assert.Equal(t, "00000001010000000000000000000000", fmt.Sprintf("%032b", opcode))
+			opcode := isa.Encode(isa.NewLock(flamego.R31))
+			assert.Equal(t, "00000001010000000000000000011111", fmt.Sprintf("%032b", opcode))
 		})
 		t.Run("Unlock", func(t *testing.T) {
-			opcode := isa.Encode(isa.NewUnlock())
-			assert.Equal(t, "00000001010100000000000000000000", fmt.Sprintf("%032b", opcode))
+			opcode := isa.Encode(isa.NewUnlock(flamego.R31))
+			assert.Equal(t, "00000001010100000000000000011111", fmt.Sprintf("%032b", opcode))
 		})
 		t.Run("Interrupt", func(t *testing.T) {
 			opcode := isa.Encode(isa.NewInterrupt(flamego.InterruptBreakpoint))
@@ -43,6 +43,38 @@ func TestEncoding(t *testing.T) {
 			opcode := isa.Encode(isa.NewUninterrupt(flamego.R31))
 			assert.Equal(t, "00000001011100000000000000011111", fmt.Sprintf("%032b", opcode))
 		})
+		t.Run("Timestamp", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewTimestamp(flamego.CounterTime, flamego.R31))
+			assert.Equal(t, "00000001100000000000000001011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Acknowledge", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewAcknowledge(flamego.R31))
+			assert.Equal(t, "00000001100100000000000000011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Send", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewSend(flamego.R29, flamego.R30, flamego.R31))
+			assert.Equal(t, "00000001101000000111011111011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Receive", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewReceive(flamego.R31))
+			assert.Equal(t, "00000001101100000000000000011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Spawn", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewSpawn(flamego.R29, flamego.R30, flamego.R31))
+			assert.Equal(t, "00000001110000000111011111011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Exit", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewExit())
+			assert.Equal(t, "00000001110100000000000000000000", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Save", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewSave(flamego.R31))
+			assert.Equal(t, "00000001111000000000000000011111", fmt.Sprintf("%032b", opcode))
+		})
+		t.Run("Restore", func(t *testing.T) {
+			opcode := isa.Encode(isa.NewRestore(flamego.R31))
+			assert.Equal(t, "00000001111100000000000000011111", fmt.Sprintf("%032b", opcode))
+		})
 	})
 	t.Run("ControlFlow", func(t *testing.T) {
 		t.Run("Jump", func(t *testing.T) {
@@ -200,16 +232,18 @@ func TestDecoding(t *testing.T) {
 			assert.Equal(t, flamego.R31, inst.DeviceIdRegister)
 		})
 		t.Run("Lock", func(t *testing.T) {
-			opcode, err := strconv.ParseUint("00000001010000000000000000000000", 2, 32)
+			opcode, err := strconv.ParseUint("00000001010000000000000000011111", 2, 32)
 			assert.NoError(t, err)
-			_, ok := isa.Decode(uint32(opcode)).(*isa.Lock)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Lock)
 			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.LockRegister)
 		})
 		t.Run("Unlock", func(t *testing.T) {
-			opcode, err := strconv.ParseUint("00000001010100000000000000000000", 2, 32)
+			opcode, err := strconv.ParseUint("00000001010100000000000000011111", 2, 32)
 			assert.NoError(t, err)
-			_, ok := isa.Decode(uint32(opcode)).(*isa.Unlock)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Unlock)
 			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.LockRegister)
 		})
 		t.Run("Interrupt", func(t *testing.T) {
 			opcode, err := strconv.ParseUint("00000001011000000000000000000001", 2, 32)
@@ -225,6 +259,66 @@ func TestDecoding(t *testing.T) {
 			assert.True(t, ok)
 			assert.Equal(t, flamego.R31, inst.AddressRegister)
 		})
+		t.Run("Timestamp", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001100000000000000001011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Timestamp)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.CounterTime, inst.Counter)
+			assert.Equal(t, flamego.R31, inst.DestinationRegister)
+		})
+		t.Run("Acknowledge", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001100100000000000000011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Acknowledge)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.DestinationRegister)
+		})
+		t.Run("Send", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001101000000111011111011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Send)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R29, inst.ContextRegister)
+			assert.Equal(t, flamego.R30, inst.MessageRegister)
+			assert.Equal(t, flamego.R31, inst.DestinationRegister)
+		})
+		t.Run("Receive", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001101100000000000000011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Receive)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.DestinationRegister)
+		})
+		t.Run("Spawn", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001110000000111011111011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Spawn)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R29, inst.ContextRegister)
+			assert.Equal(t, flamego.R30, inst.DescriptorRegister)
+			assert.Equal(t, flamego.R31, inst.DestinationRegister)
+		})
+		t.Run("Exit", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001110100000000000000000000", 2, 32)
+			assert.NoError(t, err)
+			_, ok := isa.Decode(uint32(opcode)).(*isa.Exit)
+			assert.True(t, ok)
+		})
+		t.Run("Save", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001111000000000000000011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Save)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.AddressRegister)
+		})
+		t.Run("Restore", func(t *testing.T) {
+			opcode, err := strconv.ParseUint("00000001111100000000000000011111", 2, 32)
+			assert.NoError(t, err)
+			inst, ok := isa.Decode(uint32(opcode)).(*isa.Restore)
+			assert.True(t, ok)
+			assert.Equal(t, flamego.R31, inst.AddressRegister)
+		})
 	})
 	t.Run("ControlFlow", func(t *testing.T) {
 		t.Run("Jump", func(t *testing.T) {
@@ -0,0 +1,45 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+)
+
+type Exit struct {
+}
+
+func NewExit() *Exit {
+	return &Exit{}
+}
+
+func (i *Exit) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	// Do Nothing
+	return 0, 0, 0, 0
+}
+
+func (i *Exit) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	x.SetInterrupted(false)
+	x.Sleep()
+	if parent := x.Parent(); parent >= 0 {
+		// Notify Parent
+		x.Core().Processor().Signal(flamego.ContextIdentifier(x), parent)
+	}
+	return 0, 0
+}
+
+func (i *Exit) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	// Do Nothing
+	return 0, 0
+}
+
+func (i *Exit) Store(x flamego.Context, a, b uint64) {
+	// Do Nothing
+}
+
+func (i *Exit) Retire(x flamego.Context) bool {
+	// Do Nothing
+	return true
+}
+
+func (i *Exit) String() string {
+	return "exit"
+}
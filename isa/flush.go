@@ -8,13 +8,6 @@ import (
 type Flush struct {
 	AddressRegister flamego.Register
 	Offset          uint32
-	success         bool
-	issuedL1D       bool
-	issuedL2        bool
-	issuedL3        bool
-	flushedL1D      bool
-	flushedL2       bool
-	flushedL3       bool
 }
 
 func NewFlush(a flamego.Register, o uint32) *Flush {
@@ -25,7 +18,8 @@ func NewFlush(a flamego.Register, o uint32) *Flush {
 }
 
 func (i *Flush) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Base Register
 	a := x.ReadRegister(i.AddressRegister)
 	// Load Offset
@@ -34,79 +28,81 @@ func (i *Flush) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Flush) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	address := a + b
-	if !i.issuedL1D {
+	if !s.Issues[flamego.CacheLevelL1D] {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Flush Request
 		l1d.Flush(address)
-		i.issuedL1D = true
-	} else if !i.issuedL2 && i.flushedL1D {
+		s.Issues[flamego.CacheLevelL1D] = true
+	} else if !s.Issues[flamego.CacheLevelL2] && s.Completes[flamego.CacheLevelL1D] {
 		l2 := x.Core().Cache()
 		if l2.IsBusy() || !l2.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Flush Request
 		l2.Flush(address)
-		i.issuedL2 = true
-	} else if !i.issuedL3 && i.flushedL2 {
+		s.Issues[flamego.CacheLevelL2] = true
+	} else if !s.Issues[flamego.CacheLevelL3] && s.Completes[flamego.CacheLevelL2] {
 		l3 := x.Core().Processor().Cache()
 		if l3.IsBusy() || !l3.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 
 		// Issue Flush Request
 		l3.Flush(address)
-		i.issuedL3 = true
+		s.Issues[flamego.CacheLevelL3] = true
 	}
 	return 0, 0
 }
 
 func (i *Flush) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
-	if !i.flushedL1D {
+	if !s.Completes[flamego.CacheLevelL1D] {
 		l1d := x.DataCache()
 		if l1d.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l1d.IsSuccessful() {
-			i.success = false
-			i.issuedL1D = false // Reissue Request
-			l1d.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL1D] = false // Reissue Request
+			l1d.Free()                              // Free Cache
 		} else {
-			i.flushedL1D = true
+			s.Completes[flamego.CacheLevelL1D] = true
 			l1d.Free() // Free Cache
 		}
-	} else if !i.flushedL2 {
+	} else if !s.Completes[flamego.CacheLevelL2] {
 		l2 := x.Core().Cache()
 		if l2.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l2.IsSuccessful() {
-			i.success = false
-			i.issuedL2 = false // Reissue Request
-			l2.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL2] = false // Reissue Request
+			l2.Free()                              // Free Cache
 		} else {
-			i.flushedL2 = true
+			s.Completes[flamego.CacheLevelL2] = true
 			l2.Free() // Free Cache
 		}
-	} else if !i.flushedL3 {
+	} else if !s.Completes[flamego.CacheLevelL3] {
 		l3 := x.Core().Processor().Cache()
 		if l3.IsBusy() {
-			i.success = false
+			s.Success = false
 		} else if !l3.IsSuccessful() {
-			i.success = false
-			i.issuedL3 = false // Reissue Request
-			l3.Free()          // Free Cache
+			s.Success = false
+			s.Issues[flamego.CacheLevelL3] = false // Reissue Request
+			l3.Free()                              // Free Cache
 		} else {
-			i.flushedL3 = true
+			s.Completes[flamego.CacheLevelL3] = true
 			l3.Free() // Free Cache
 		}
 	}
@@ -118,7 +114,8 @@ func (i *Flush) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Flush) Retire(x flamego.Context) bool {
-	if i.success && i.flushedL1D && i.flushedL2 && i.flushedL3 {
+	s := x.State()
+	if s.Success && s.Completes[flamego.CacheLevelL1D] && s.Completes[flamego.CacheLevelL2] && s.Completes[flamego.CacheLevelL3] {
 		x.IncrementProgramCounter()
 		return true
 	}
@@ -5,7 +5,6 @@ import (
 )
 
 type Halt struct {
-	success bool
 }
 
 func NewHalt() *Halt {
@@ -13,16 +12,18 @@ func NewHalt() *Halt {
 }
 
 func (i *Halt) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Do Nothing
 	return 0, 0, 0, 0
 }
 
 func (i *Halt) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Halt only allowed in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
 		return 0, 0
 	}
 	x.Core().Processor().Halt()
@@ -16,27 +16,33 @@ func NewInterrupt(value flamego.InterruptValue) *Interrupt {
 }
 
 func (i *Interrupt) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	// Load Interrupt Vector Table
-	return x.ReadRegister(flamego.RInterruptVectorTable), uint64(i.Value), 0, 0
+	// Load Interrupt Vector Table and Program Counter
+	return x.ReadRegister(flamego.RInterruptVectorTable), uint64(i.Value), x.ReadRegister(flamego.RProgramCounter), 0
 }
 
 func (i *Interrupt) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
 	// Calculate address of Interrupt Service Routine by add interrupt value to Interrupt Vector Table
-	return a + b, 0
+	return a + b, c
 }
 
 func (i *Interrupt) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
 	// Do Nothing
-	return a, 0
+	return a, b
 }
 
 func (i *Interrupt) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
 	// Jump to Interrupt Service Routine by updating the Program Counter
 	x.SetProgramCounter(a)
+	s.Address = b
 }
 
 func (i *Interrupt) Retire(x flamego.Context) bool {
+	s := x.State()
 	x.SetInterrupted(true)
+	// Record where the interrupt occurred, and why
+	x.WriteRegister(flamego.RInterruptReturn, s.Address)
+	x.WriteRegister(flamego.RInterruptValue, uint64(i.Value))
 	return true
 }
 
@@ -2,7 +2,6 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
-	"encoding/binary"
 	"fmt"
 )
 
@@ -10,8 +9,6 @@ type Load struct {
 	AddressRegister     flamego.Register
 	Offset              uint32
 	DestinationRegister flamego.Register
-	success             bool
-	issued              bool
 }
 
 func NewLoad(a flamego.Register, o uint32, r flamego.Register) *Load {
@@ -23,7 +20,8 @@ func NewLoad(a flamego.Register, o uint32, r flamego.Register) *Load {
 }
 
 func (i *Load) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Base Register
 	a := x.ReadRegister(i.AddressRegister)
 	// Load Address
@@ -32,44 +30,44 @@ func (i *Load) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Load) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
-	if !i.issued {
+	s := x.State()
+	if !s.Issued {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 		// Issue Read Request
 		l1d.Read(a + b)
-		i.issued = true
+		s.Issued = true
 	}
 	return 0, 0
 }
 
 func (i *Load) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
 	l1d := x.DataCache()
 	if l1d.IsBusy() {
-		i.success = false
+		s.Success = false
 	} else if !l1d.IsSuccessful() {
-		i.success = false
-		i.issued = false // Reissue Request
+		s.Success = false
+		s.Issued = false // Reissue Request
 		l1d.Free()       // Free Cache
 	} else {
 		// Copy Data from Bus
-		buffer := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			buffer[i] = l1d.Bus().Read(i)
-		}
+		value := readBus(l1d.Bus())
 		l1d.Free() // Free Cache
-		return binary.BigEndian.Uint64(buffer), 0
+		return value, 0
 	}
 	return 0, 0
 }
 
 func (i *Load) Store(x flamego.Context, a, b uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return
 	}
 	// Write Destination Register
@@ -77,7 +75,8 @@ func (i *Load) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Load) Retire(x flamego.Context) bool {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		x.IncrementProgramCounter()
 		return true
 	}
@@ -2,30 +2,41 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
+	"fmt"
 )
 
 type Lock struct {
-	success bool
+	LockRegister flamego.Register
 }
 
-func NewLock() *Lock {
-	return &Lock{}
+func NewLock(r flamego.Register) *Lock {
+	return &Lock{
+		LockRegister: r,
+	}
 }
 
 func (i *Lock) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
-	// Do Nothing
-	return 0, 0, 0, 0
+	s := x.State()
+	s.Success = true
+	// Load Lock Identifier
+	return x.ReadRegister(i.LockRegister), 0, 0, 0
 }
 
 func (i *Lock) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Hardware Lock acquirable only in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
+		return 0, 0
+	}
+	if a >= flamego.LockCount {
+		// Unknown Hardware Lock is never acquired, so the instruction retires without effect
+		s.Index = -1
 		return 0, 0
 	}
-	x.SetRequiresLock(true)
+	s.Index = int(a)
+	x.SetRequiresLock(s.Index, true)
 	return 0, 0
 }
 
@@ -39,7 +50,12 @@ func (i *Lock) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Lock) Retire(x flamego.Context) bool {
-	if i.success && x.AcquiredLock() {
+	s := x.State()
+	if !s.Success {
+		// Not retryable
+		return true
+	}
+	if s.Index < 0 || x.AcquiredLock(s.Index) {
 		x.IncrementProgramCounter()
 		return true
 	}
@@ -47,5 +63,5 @@ func (i *Lock) Retire(x flamego.Context) bool {
 }
 
 func (i *Lock) String() string {
-	return "lock"
+	return fmt.Sprintf("lock %s", i.LockRegister)
 }
@@ -0,0 +1,122 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+)
+
+// Registers is a set of registers, one bit per register.
+type Registers uint32
+
+// RegisterSet returns the set of the given registers.
+func RegisterSet(registers ...flamego.Register) Registers {
+	var r Registers
+	for _, register := range registers {
+		r |= 1 << register
+	}
+	return r
+}
+
+// Contains returns true if the set contains any of the given registers.
+func (r Registers) Contains(registers Registers) bool {
+	return r&registers != 0
+}
+
+// maskRegisters returns the general purpose registers selected by a push or pop mask, the MSB selects r16 and the LSB selects r31.
+func maskRegisters(mask uint16) Registers {
+	var r Registers
+	for index := 0; index < 16; index++ {
+		if mask&(1<<index) != 0 {
+			r |= 1 << (flamego.R31 - flamego.Register(index))
+		}
+	}
+	return r
+}
+
+// contextState is the set of registers saved and restored by a context switch, R4 - R31, excluding the Program Counter.
+const contextState = Registers(0xfffffff0) &^ (1 << flamego.RProgramCounter)
+
+// Operands returns the registers read, and the registers written, by the instruction.
+// The Program Counter is excluded as every instruction updates it.
+func Operands(instruction flamego.Instruction) (Registers, Registers) {
+	switch i := instruction.(type) {
+	case *LoadC:
+		return 0, RegisterSet(i.DestinationRegister)
+	case *Jump:
+		return RegisterSet(i.ConditionRegister), 0
+	case *Load:
+		return RegisterSet(i.AddressRegister), RegisterSet(i.DestinationRegister)
+	case *Store:
+		return RegisterSet(i.AddressRegister, i.SourceRegister), 0
+	case *Clear:
+		return RegisterSet(i.AddressRegister), 0
+	case *Flush:
+		return RegisterSet(i.AddressRegister), 0
+	case *Not:
+		return RegisterSet(i.SourceRegister), RegisterSet(i.DestinationRegister)
+	case *And:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Or:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Xor:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *LeftShift:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *RightShift:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Add:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Subtract:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Multiply:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Divide:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Modulo:
+		return RegisterSet(i.Source1Register, i.Source2Register), RegisterSet(i.DestinationRegister)
+	case *Push:
+		stack := RegisterSet(flamego.RStackPointer, flamego.RStackLimit)
+		return stack | maskRegisters(i.Mask), RegisterSet(flamego.RStackPointer)
+	case *Pop:
+		stack := RegisterSet(flamego.RStackPointer, flamego.RStackStart)
+		return stack, RegisterSet(flamego.RStackPointer) | maskRegisters(i.Mask)
+	case *Call:
+		return RegisterSet(i.AddressRegister, flamego.RStackPointer, flamego.RStackLimit), RegisterSet(flamego.RStackPointer)
+	case *Return:
+		return RegisterSet(flamego.RStackPointer, flamego.RStackStart), RegisterSet(flamego.RStackPointer)
+	case *Signal:
+		return RegisterSet(i.DeviceIdRegister), 0
+	case *Lock:
+		return RegisterSet(i.LockRegister), 0
+	case *Unlock:
+		return RegisterSet(i.LockRegister), 0
+	case *Interrupt:
+		return RegisterSet(flamego.RInterruptVectorTable), RegisterSet(flamego.RInterruptReturn, flamego.RInterruptValue)
+	case *Uninterrupt:
+		return RegisterSet(i.AddressRegister), 0
+	case *Timestamp:
+		return 0, RegisterSet(i.DestinationRegister)
+	case *Acknowledge:
+		return 0, RegisterSet(i.DestinationRegister)
+	case *Send:
+		return RegisterSet(i.ContextRegister, i.MessageRegister), 0
+	case *Receive:
+		return 0, RegisterSet(i.DestinationRegister)
+	case *Spawn:
+		return RegisterSet(i.ContextRegister, i.DescriptorRegister), RegisterSet(i.DestinationRegister)
+	case *Save:
+		return RegisterSet(i.AddressRegister) | contextState, 0
+	case *Restore:
+		return RegisterSet(i.AddressRegister), contextState
+	}
+	// Halt, Noop, Sleep, Exit
+	return 0, 0
+}
+
+// ReadsMemory returns true if the instruction writes data read from memory into a register.
+func ReadsMemory(instruction flamego.Instruction) bool {
+	switch instruction.(type) {
+	case *Load, *Pop, *Return, *Restore:
+		return true
+	}
+	return false
+}
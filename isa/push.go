@@ -2,39 +2,39 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
-	"encoding/binary"
 	"fmt"
 )
 
 type Push struct {
-	Mask    uint16
-	success bool
-	issued  bool
-	index   uint8
+	Mask uint16
 }
 
 func NewPush(m uint16) *Push {
 	return &Push{
-		Mask:  m,  // 16 bits representing r16..r31
-		index: 15, // Start with r16 (MSB), interate up to r31 (LSB)
+		Mask: m, // 16 bits representing r16..r31
 	}
 }
 
 func (i *Push) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Stack Pointer Register
 	a := x.ReadRegister(flamego.RStackPointer)
 	// Load Stack Limit Register
 	b := x.ReadRegister(flamego.RStackLimit)
+	if !s.Loaded {
+		// Registers remaining to be pushed
+		s.Mask = i.Mask
+		s.Loaded = true
+	}
 	var c uint64
-	for ; i.index >= 0; i.index-- {
-		m := (uint16(1) << i.index)
-		if i.Mask&m != 0 {
-			r := flamego.R31 - flamego.Register(i.index)
+	// Start with r16 (MSB), interate up to r31 (LSB)
+	for index := 15; index >= 0; index-- {
+		m := (uint16(1) << index)
+		if s.Mask&m != 0 {
+			r := flamego.R31 - flamego.Register(index)
 			// Load Source Register
 			c = x.ReadRegister(r)
-			// Clear bit from mask
-			i.Mask &= ^m
 			break
 		}
 	}
@@ -42,38 +42,36 @@ func (i *Push) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Push) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if a >= b {
 		x.Error(flamego.InterruptStackOverflowError)
-		i.success = false
-	} else if !i.issued {
+		s.Success = false
+	} else if !s.Issued {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 		// Copy Data to Bus
-		buffer := make([]byte, 8)
-		binary.BigEndian.PutUint64(buffer, c)
-		for i := 0; i < 8; i++ {
-			l1d.Bus().Write(i, buffer[i])
-		}
+		writeBus(l1d.Bus(), c)
 		// Issue Write Request
 		l1d.Write(a)
-		i.issued = true
+		s.Issued = true
 	}
 	return a, 0
 }
 
 func (i *Push) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
 	l1d := x.DataCache()
 	if l1d.IsBusy() {
-		i.success = false
+		s.Success = false
 	} else if !l1d.IsSuccessful() {
-		i.success = false
-		i.issued = false // Reissue Request
+		s.Success = false
+		s.Issued = false // Reissue Request
 		l1d.Free()       // Free Cache
 	} else {
 		l1d.Free() // Free Cache
@@ -82,21 +80,31 @@ func (i *Push) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
 }
 
 func (i *Push) Store(x flamego.Context, a, b uint64) {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		// Increment Stack Pointer
 		p := a + flamego.DataSize
 		x.WriteRegister(flamego.RStackPointer, p)
+		// Clear bit of pushed register from mask, so a failed write is retried with the same register
+		for index := 15; index >= 0; index-- {
+			m := (uint16(1) << index)
+			if s.Mask&m != 0 {
+				s.Mask &= ^m
+				break
+			}
+		}
 	}
 }
 
 func (i *Push) Retire(x flamego.Context) bool {
-	if i.success {
-		if i.Mask == 0 {
+	s := x.State()
+	if s.Success {
+		if s.Mask == 0 {
 			x.IncrementProgramCounter()
 			return true
 		} else {
 			// Reset issued flag
-			i.issued = false
+			s.Issued = false
 		}
 	}
 	return false
@@ -0,0 +1,60 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Receive struct {
+	DestinationRegister flamego.Register
+}
+
+func NewReceive(r flamego.Register) *Receive {
+	return &Receive{
+		DestinationRegister: r,
+	}
+}
+
+func (i *Receive) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Do Nothing
+	return 0, 0, 0, 0
+}
+
+func (i *Receive) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	message, ok := x.Core().Processor().Receive(flamego.ContextIdentifier(x))
+	if !ok {
+		// Wait for a message, the instruction will be fetched again when one is delivered
+		x.Wait()
+		s.Success = false
+		return 0, 0
+	}
+	return message, 0
+}
+
+func (i *Receive) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	// Pass Through
+	return a, 0
+}
+
+func (i *Receive) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if s.Success {
+		// Write Destination Register
+		x.WriteRegister(i.DestinationRegister, a)
+	}
+}
+
+func (i *Receive) Retire(x flamego.Context) bool {
+	s := x.State()
+	if s.Success {
+		x.IncrementProgramCounter()
+	}
+	return true
+}
+
+func (i *Receive) String() string {
+	return fmt.Sprintf("receive %s", i.DestinationRegister)
+}
@@ -0,0 +1,102 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Restore struct {
+	AddressRegister flamego.Register
+}
+
+func NewRestore(r flamego.Register) *Restore {
+	return &Restore{
+		AddressRegister: r,
+	}
+}
+
+func (i *Restore) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	if !s.Loaded {
+		// Load Block Address once, as the Address Register may be restored
+		s.Address = x.ReadRegister(i.AddressRegister)
+		s.Loaded = true
+	}
+	return s.Address, 0, 0, 0
+}
+
+func (i *Restore) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Restore only allowed in an interrupt
+		x.Error(flamego.InterruptUnsupportedOperationError)
+		s.Success = false
+	} else if !s.Issued {
+		l1d := x.DataCache()
+		if l1d.IsBusy() || !l1d.IsFree() {
+			s.Success = false // Cache Unavailable
+			return 0, 0
+		}
+		// Issue Read Request
+		l1d.Read(a + uint64(s.Index)*flamego.DataSize)
+		s.Issued = true
+	}
+	return 0, 0
+}
+
+func (i *Restore) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	s := x.State()
+	if !s.Success {
+		return 0, 0
+	}
+	l1d := x.DataCache()
+	if l1d.IsBusy() {
+		s.Success = false
+	} else if !l1d.IsSuccessful() {
+		s.Success = false
+		s.Issued = false // Reissue Request
+		l1d.Free()       // Free Cache
+	} else {
+		// Copy Data from Bus
+		value := readBus(l1d.Bus())
+		l1d.Free() // Free Cache
+		return value, 0
+	}
+	return 0, 0
+}
+
+func (i *Restore) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if !s.Success {
+		return
+	}
+	r := flamego.R4 + flamego.Register(s.Index)
+	if r != flamego.RProgramCounter {
+		// Write Destination Register, the Program Counter is not restored as the interrupt continues
+		x.WriteRegister(r, a)
+	}
+	s.Index++
+}
+
+func (i *Restore) Retire(x flamego.Context) bool {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Not retryable
+		return true
+	}
+	if s.Success {
+		if s.Index == flamego.ContextStateLength {
+			x.IncrementProgramCounter()
+			return true
+		} else {
+			// Reset issued flag
+			s.Issued = false
+		}
+	}
+	return false
+}
+
+func (i *Restore) String() string {
+	return fmt.Sprintf("restore %s", i.AddressRegister)
+}
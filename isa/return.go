@@ -2,12 +2,9 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
-	"encoding/binary"
 )
 
 type Return struct {
-	success bool
-	issued  bool
 }
 
 func NewReturn() *Return {
@@ -15,7 +12,8 @@ func NewReturn() *Return {
 }
 
 func (i *Return) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Stack Pointer Register
 	a := x.ReadRegister(flamego.RStackPointer)
 	// Load Stack Start Register
@@ -24,51 +22,51 @@ func (i *Return) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Return) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	// Decrement Stack Pointer
 	a -= flamego.DataSize
 
 	if a < b {
 		x.Error(flamego.InterruptStackUnderflowError)
-		i.success = false
-	} else if !i.issued {
+		s.Success = false
+	} else if !s.Issued {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 		// Issue Read Request
 		l1d.Read(a)
-		i.issued = true
+		s.Issued = true
 	}
 	return a, 0
 }
 
 func (i *Return) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
 	l1d := x.DataCache()
 	if l1d.IsBusy() {
-		i.success = false
+		s.Success = false
 	} else if !l1d.IsSuccessful() {
-		i.success = false
-		i.issued = false // Reissue Request
+		s.Success = false
+		s.Issued = false // Reissue Request
 		l1d.Free()       // Free Cache
 	} else {
 		// Copy Data from Bus
-		buffer := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			buffer[i] = l1d.Bus().Read(i)
-		}
+		value := readBus(l1d.Bus())
 		l1d.Free() // Free Cache
-		b := binary.BigEndian.Uint64(buffer)
+		b := value
 		return a, b
 	}
 	return a, 0
 }
 
 func (i *Return) Store(x flamego.Context, a, b uint64) {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		// Update Stack Pointer
 		x.WriteRegister(flamego.RStackPointer, a)
 		// Update Program Counter
@@ -77,7 +75,8 @@ func (i *Return) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Return) Retire(x flamego.Context) bool {
-	return i.success
+	s := x.State()
+	return s.Success
 }
 
 func (i *Return) String() string {
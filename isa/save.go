@@ -0,0 +1,94 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Save struct {
+	AddressRegister flamego.Register
+}
+
+func NewSave(r flamego.Register) *Save {
+	return &Save{
+		AddressRegister: r,
+	}
+}
+
+func (i *Save) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Load Block Address
+	a := x.ReadRegister(i.AddressRegister)
+	// Load Source Register, starting with R4 and iterating up to R31
+	b := x.ReadRegister(flamego.R4 + flamego.Register(s.Index))
+	return a, b, 0, 0
+}
+
+func (i *Save) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Save only allowed in an interrupt
+		x.Error(flamego.InterruptUnsupportedOperationError)
+		s.Success = false
+	} else if !s.Issued {
+		l1d := x.DataCache()
+		if l1d.IsBusy() || !l1d.IsFree() {
+			s.Success = false // Cache Unavailable
+			return 0, 0
+		}
+		// Copy Data to Bus
+		writeBus(l1d.Bus(), b)
+		// Issue Write Request
+		l1d.Write(a + uint64(s.Index)*flamego.DataSize)
+		s.Issued = true
+	}
+	return 0, 0
+}
+
+func (i *Save) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	s := x.State()
+	if !s.Success {
+		return 0, 0
+	}
+	l1d := x.DataCache()
+	if l1d.IsBusy() {
+		s.Success = false
+	} else if !l1d.IsSuccessful() {
+		s.Success = false
+		s.Issued = false // Reissue Request
+		l1d.Free()       // Free Cache
+	} else {
+		l1d.Free() // Free Cache
+	}
+	return 0, 0
+}
+
+func (i *Save) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if s.Success {
+		s.Index++
+	}
+}
+
+func (i *Save) Retire(x flamego.Context) bool {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Not retryable
+		return true
+	}
+	if s.Success {
+		if s.Index == flamego.ContextStateLength {
+			x.IncrementProgramCounter()
+			return true
+		} else {
+			// Reset issued flag
+			s.Issued = false
+		}
+	}
+	return false
+}
+
+func (i *Save) String() string {
+	return fmt.Sprintf("save %s", i.AddressRegister)
+}
@@ -0,0 +1,65 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Send struct {
+	ContextRegister     flamego.Register
+	MessageRegister     flamego.Register
+	DestinationRegister flamego.Register
+}
+
+func NewSend(c, m, d flamego.Register) *Send {
+	return &Send{
+		ContextRegister:     c,
+		MessageRegister:     m,
+		DestinationRegister: d,
+	}
+}
+
+func (i *Send) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Load Context Identifier and Message
+	return x.ReadRegister(i.ContextRegister), x.ReadRegister(i.MessageRegister), 0, 0
+}
+
+func (i *Send) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	target := -1
+	if a < flamego.CoreCount*flamego.ContextCount {
+		target = int(a)
+	}
+	// Deliver Message at the end of the cycle, identifiers which are not contexts are undeliverable
+	x.Core().Processor().Send(flamego.ContextIdentifier(x), target, b)
+	return 0, 0
+}
+
+func (i *Send) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	// Do Nothing
+	return 0, 0
+}
+
+func (i *Send) Store(x flamego.Context, a, b uint64) {
+	// Do Nothing
+}
+
+func (i *Send) Retire(x flamego.Context) bool {
+	var result uint64
+	switch x.Core().Processor().Delivery(flamego.ContextIdentifier(x)) {
+	case flamego.MessageRejected:
+		// Retry while the mailbox is full
+		return false
+	case flamego.MessageDelivered:
+		result = 1
+	}
+	// Write Destination Register
+	x.WriteRegister(i.DestinationRegister, result)
+	x.IncrementProgramCounter()
+	return true
+}
+
+func (i *Send) String() string {
+	return fmt.Sprintf("send %s %s %s", i.ContextRegister, i.MessageRegister, i.DestinationRegister)
+}
@@ -7,7 +7,6 @@ import (
 
 type Signal struct {
 	DeviceIdRegister flamego.Register
-	success          bool
 }
 
 func NewSignal(r flamego.Register) *Signal {
@@ -17,19 +16,21 @@ func NewSignal(r flamego.Register) *Signal {
 }
 
 func (i *Signal) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Device Address
 	return x.ReadRegister(i.DeviceIdRegister), 0, 0, 0
 }
 
 func (i *Signal) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Signal only allowed in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
 		return 0, 0
 	}
-	x.Core().Processor().Signal(int(a))
+	x.Core().Processor().Signal(flamego.ContextIdentifier(x), int(a))
 	return 0, 0
 }
 
@@ -43,7 +44,8 @@ func (i *Signal) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Signal) Retire(x flamego.Context) bool {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		x.IncrementProgramCounter()
 	}
 	return true
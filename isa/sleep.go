@@ -5,7 +5,6 @@ import (
 )
 
 type Sleep struct {
-	success bool
 }
 
 func NewSleep() *Sleep {
@@ -13,16 +12,18 @@ func NewSleep() *Sleep {
 }
 
 func (i *Sleep) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Do Nothing
 	return 0, 0, 0, 0
 }
 
 func (i *Sleep) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Sleep only allowed in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
 		return 0, 0
 	}
 	x.SetInterrupted(false)
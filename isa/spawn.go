@@ -0,0 +1,108 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Spawn struct {
+	ContextRegister     flamego.Register
+	DescriptorRegister  flamego.Register
+	DestinationRegister flamego.Register
+}
+
+func NewSpawn(c, d, r flamego.Register) *Spawn {
+	return &Spawn{
+		ContextRegister:     c,
+		DescriptorRegister:  d,
+		DestinationRegister: r,
+	}
+}
+
+func (i *Spawn) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Load Context Identifier and Descriptor Address
+	return x.ReadRegister(i.ContextRegister), x.ReadRegister(i.DescriptorRegister), 0, 0
+}
+
+func (i *Spawn) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Spawn only allowed in an interrupt
+		x.Error(flamego.InterruptUnsupportedOperationError)
+		s.Success = false
+	} else if !s.Issued {
+		l1d := x.DataCache()
+		if l1d.IsBusy() || !l1d.IsFree() {
+			s.Success = false // Cache Unavailable
+			return 0, 0
+		}
+		// Issue Read Request for the next Descriptor Register
+		l1d.Read(b + uint64(s.Index)*flamego.DataSize)
+		s.Issued = true
+	}
+	return a, 0
+}
+
+func (i *Spawn) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	s := x.State()
+	if !s.Success {
+		return 0, 0
+	}
+	l1d := x.DataCache()
+	if l1d.IsBusy() {
+		s.Success = false
+	} else if !l1d.IsSuccessful() {
+		s.Success = false
+		s.Issued = false // Reissue Request
+		l1d.Free()       // Free Cache
+	} else {
+		// Copy Data from Bus
+		value := readBus(l1d.Bus())
+		l1d.Free() // Free Cache
+		return a, value
+	}
+	return a, 0
+}
+
+func (i *Spawn) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if !s.Success {
+		return
+	}
+	s.Registers[s.Index] = b
+	s.Index++
+	if s.Index == flamego.SpawnDescriptorLength {
+		// Start Context once the Descriptor is loaded, at the end of the cycle
+		x.Core().Processor().Spawn(flamego.ContextIdentifier(x), int(a), s.Registers[:])
+	}
+}
+
+func (i *Spawn) Retire(x flamego.Context) bool {
+	s := x.State()
+	if !x.IsInterrupted() {
+		// Not retryable
+		return true
+	}
+	if s.Success {
+		if s.Index == flamego.SpawnDescriptorLength {
+			var result uint64
+			if x.Core().Processor().Spawned(flamego.ContextIdentifier(x)) {
+				result = 1
+			}
+			// Write Destination Register
+			x.WriteRegister(i.DestinationRegister, result)
+			x.IncrementProgramCounter()
+			return true
+		} else {
+			// Reset issued flag
+			s.Issued = false
+		}
+	}
+	return false
+}
+
+func (i *Spawn) String() string {
+	return fmt.Sprintf("spawn %s %s %s", i.ContextRegister, i.DescriptorRegister, i.DestinationRegister)
+}
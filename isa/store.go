@@ -2,7 +2,6 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
-	"encoding/binary"
 	"fmt"
 )
 
@@ -10,8 +9,6 @@ type Store struct {
 	AddressRegister flamego.Register
 	Offset          uint32
 	SourceRegister  flamego.Register
-	success         bool
-	issued          bool
 }
 
 func NewStore(a flamego.Register, o uint32, r flamego.Register) *Store {
@@ -23,7 +20,8 @@ func NewStore(a flamego.Register, o uint32, r flamego.Register) *Store {
 }
 
 func (i *Store) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Base Register
 	a := x.ReadRegister(i.AddressRegister)
 	// Load Offset
@@ -34,35 +32,33 @@ func (i *Store) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
 }
 
 func (i *Store) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
-	if !i.issued {
+	s := x.State()
+	if !s.Issued {
 		l1d := x.DataCache()
 		if l1d.IsBusy() || !l1d.IsFree() {
-			i.success = false // Cache Unavailable
+			s.Success = false // Cache Unavailable
 			return 0, 0
 		}
 		// Copy Data to Bus
-		buffer := make([]byte, 8)
-		binary.BigEndian.PutUint64(buffer, c)
-		for i := 0; i < 8; i++ {
-			l1d.Bus().Write(i, buffer[i])
-		}
+		writeBus(l1d.Bus(), c)
 		// Issue Write Request
 		l1d.Write(a + b)
-		i.issued = true
+		s.Issued = true
 	}
 	return 0, 0
 }
 
 func (i *Store) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
-	if !i.success {
+	s := x.State()
+	if !s.Success {
 		return 0, 0
 	}
 	l1d := x.DataCache()
 	if l1d.IsBusy() {
-		i.success = false
+		s.Success = false
 	} else if !l1d.IsSuccessful() {
-		i.success = false
-		i.issued = false // Reissue Request
+		s.Success = false
+		s.Issued = false // Reissue Request
 		l1d.Free()       // Free Cache
 	} else {
 		l1d.Free() // Free Cache
@@ -75,7 +71,8 @@ func (i *Store) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Store) Retire(x flamego.Context) bool {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		x.IncrementProgramCounter()
 		return true
 	}
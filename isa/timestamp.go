@@ -0,0 +1,66 @@
+package isa
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+type Timestamp struct {
+	Counter             flamego.Counter
+	DestinationRegister flamego.Register
+}
+
+func NewTimestamp(c flamego.Counter, r flamego.Register) *Timestamp {
+	return &Timestamp{
+		Counter:             c,
+		DestinationRegister: r,
+	}
+}
+
+func (i *Timestamp) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
+	s := x.State()
+	s.Success = true
+	// Do Nothing
+	return 0, 0, 0, 0
+}
+
+func (i *Timestamp) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
+	// Read Counter
+	switch i.Counter {
+	case flamego.CounterCycle:
+		return x.Core().Processor().Cycle(), 0
+	case flamego.CounterInstruction:
+		return x.RetiredInstructions(), 0
+	case flamego.CounterTime:
+		return x.Core().Processor().Time(), 0
+	}
+	x.Error(flamego.InterruptUnsupportedOperationError)
+	s.Success = false
+	return 0, 0
+}
+
+func (i *Timestamp) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
+	// Pass Through
+	return a, 0
+}
+
+func (i *Timestamp) Store(x flamego.Context, a, b uint64) {
+	s := x.State()
+	if s.Success {
+		// Write Destination Register
+		x.WriteRegister(i.DestinationRegister, a)
+	}
+}
+
+func (i *Timestamp) Retire(x flamego.Context) bool {
+	s := x.State()
+	if s.Success {
+		x.IncrementProgramCounter()
+	}
+	return true
+}
+
+func (i *Timestamp) String() string {
+	return fmt.Sprintf("timestamp %d %s", i.Counter, i.DestinationRegister)
+}
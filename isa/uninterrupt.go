@@ -7,7 +7,6 @@ import (
 
 type Uninterrupt struct {
 	AddressRegister flamego.Register
-	success         bool
 }
 
 func NewUninterrupt(r flamego.Register) *Uninterrupt {
@@ -17,16 +16,18 @@ func NewUninterrupt(r flamego.Register) *Uninterrupt {
 }
 
 func (i *Uninterrupt) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
+	s := x.State()
+	s.Success = true
 	// Load Return Address
 	return x.ReadRegister(i.AddressRegister), 0, 0, 0
 }
 
 func (i *Uninterrupt) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Uinterrupt only allowed in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
 		return 0, 0
 	}
 	return a, 0
@@ -38,14 +39,16 @@ func (i *Uninterrupt) Format(x flamego.Context, a, b uint64) (uint64, uint64) {
 }
 
 func (i *Uninterrupt) Store(x flamego.Context, a, b uint64) {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		// Jump out of interrupt by updating the program counter
 		x.SetProgramCounter(a)
 	}
 }
 
 func (i *Uninterrupt) Retire(x flamego.Context) bool {
-	if i.success {
+	s := x.State()
+	if s.Success {
 		x.SetInterrupted(false)
 	}
 	return true
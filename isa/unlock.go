@@ -2,30 +2,41 @@ package isa
 
 import (
 	"aletheiaware.com/flamego"
+	"fmt"
 )
 
 type Unlock struct {
-	success bool
+	LockRegister flamego.Register
 }
 
-func NewUnlock() *Unlock {
-	return &Unlock{}
+func NewUnlock(r flamego.Register) *Unlock {
+	return &Unlock{
+		LockRegister: r,
+	}
 }
 
 func (i *Unlock) Load(x flamego.Context) (uint64, uint64, uint64, uint64) {
-	i.success = true
-	// Do Nothing
-	return 0, 0, 0, 0
+	s := x.State()
+	s.Success = true
+	// Load Lock Identifier
+	return x.ReadRegister(i.LockRegister), 0, 0, 0
 }
 
 func (i *Unlock) Execute(x flamego.Context, a, b, c, d uint64) (uint64, uint64) {
+	s := x.State()
 	if !x.IsInterrupted() {
 		// Hardware Lock only releasable in an interrupt
 		x.Error(flamego.InterruptUnsupportedOperationError)
-		i.success = false
+		s.Success = false
+		return 0, 0
+	}
+	if a >= flamego.LockCount {
+		// Unknown Hardware Lock is never held, so the instruction retires without effect
+		s.Index = -1
 		return 0, 0
 	}
-	x.SetRequiresLock(false)
+	s.Index = int(a)
+	x.SetRequiresLock(s.Index, false)
 	return 0, 0
 }
 
@@ -39,7 +50,12 @@ func (i *Unlock) Store(x flamego.Context, a, b uint64) {
 }
 
 func (i *Unlock) Retire(x flamego.Context) bool {
-	if i.success && !x.AcquiredLock() {
+	s := x.State()
+	if !s.Success {
+		// Not retryable
+		return true
+	}
+	if s.Index < 0 || !x.AcquiredLock(s.Index) {
 		x.IncrementProgramCounter()
 		return true
 	}
@@ -47,5 +63,5 @@ func (i *Unlock) Retire(x flamego.Context) bool {
 }
 
 func (i *Unlock) String() string {
-	return "unlock"
+	return fmt.Sprintf("unlock %s", i.LockRegister)
 }
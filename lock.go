@@ -0,0 +1,26 @@
+package flamego
+
+import (
+	"fmt"
+)
+
+const LockCount = 64
+
+type LockPolicy uint8
+
+const (
+	// Locks are granted in the order they were requested
+	LockPolicyFIFO LockPolicy = iota
+	// Locks are granted to the next waiting context after the previous holder
+	LockPolicyRoundRobin
+)
+
+func (p LockPolicy) String() string {
+	switch p {
+	case LockPolicyFIFO:
+		return "fifo"
+	case LockPolicyRoundRobin:
+		return "roundrobin"
+	}
+	return fmt.Sprintf("LockPolicy 0x%02x", uint8(p))
+}
@@ -8,9 +8,34 @@ type Processor interface {
 	AddCore(Core)
 	Device(int) Device
 	AddDevice(Device)
+	InterruptController() InterruptController
+
+	Cycle() uint64
+	Time() uint64
 
 	Halt()
 	HasHalted() bool
 
-	Signal(int)
+	Signal(int, int)
+
+	Send(int, int, uint64)
+	Delivery(int) Delivery
+	Receive(int) (uint64, bool)
+
+	Spawn(int, int, []uint64)
+	Spawned(int) bool
+
+	RequireLock(int, int, bool)
 }
+
+// Delivery is the outcome of the last message sent by a context.
+type Delivery uint8
+
+const (
+	// Message was added to the mailbox of the target
+	MessageDelivered Delivery = iota
+	// Message was rejected by the full mailbox of the target, and is sent again
+	MessageRejected
+	// Target is not a context, so the message was dropped
+	MessageUndeliverable
+)
@@ -25,8 +25,8 @@ const (
 	R11                 // PR, Stack Limit
 	R12                 // PR, Data Start
 	R13                 // PR, Data Limit
-	R14                 // PR, Reserved
-	R15                 // PR, Reserved
+	R14                 // PR, Interrupt Return Address (Program Counter when interrupted)
+	R15                 // PR, Interrupt Value
 	R16                 // GP
 	R17                 // GP
 	R18                 // GP
@@ -60,6 +60,8 @@ const (
 	RStackLimit           = R11
 	RDataStart            = R12
 	RDataLimit            = R13
+	RInterruptReturn      = R14
+	RInterruptValue       = R15
 )
 
 func (r Register) String() string {
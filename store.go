@@ -12,4 +12,8 @@ type Store interface {
 
 	Read(uint64)
 	Write(uint64)
+
+	// Burst transactions transfer the given number of bytes, up to a whole line of the upper store, in a single request.
+	ReadBurst(uint64, int)
+	WriteBurst(uint64, int)
 }
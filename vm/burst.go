@@ -0,0 +1,26 @@
+package vm
+
+// Burst is the timing of the burst transactions served by a store.
+// Caches fill and write back whole lines in bursts when their Bandwidth is non-zero.
+type Burst struct {
+	Latency   int // Unit: Clocks of the store before the first bytes are transferred
+	Bandwidth int // Unit: Bytes transferred per clock of the store, zero transfers the size of the bus per clock
+}
+
+// IsEnabled returns true if a cache with this timing requests lines from its lower store in bursts.
+func (b Burst) IsEnabled() bool {
+	return b.Bandwidth > 0
+}
+
+// Clocks returns the clocks of the store to transfer the given number of bytes over a bus of the given size, at least one.
+func (b Burst) Clocks(length, size int) int {
+	bandwidth := b.Bandwidth
+	if bandwidth <= 0 {
+		bandwidth = size
+	}
+	clocks := b.Latency + (length+bandwidth-1)/bandwidth
+	if clocks < 1 {
+		clocks = 1
+	}
+	return clocks
+}
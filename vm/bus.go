@@ -6,24 +6,47 @@ import (
 
 func NewBus(size int) *Bus {
 	return &Bus{
-		size:  size,
-		valid: make([]bool, size),
-		dirty: make([]bool, size),
-		data:  make([]byte, size),
+		size:   size,
+		length: size,
+		valid:  make([]bool, size),
+		dirty:  make([]bool, size),
+		data:   make([]byte, size),
 	}
 }
 
 type Bus struct {
-	size  int
-	valid []bool
-	dirty []bool
-	data  []byte
+	size   int
+	length int
+	valid  []bool
+	dirty  []bool
+	data   []byte
 }
 
 func (b *Bus) Size() int {
 	return b.size
 }
 
+func (b *Bus) Length() int {
+	return b.length
+}
+
+// SetLength sets the number of bytes transferred by the current request, growing the bus to hold a burst.
+func (b *Bus) SetLength(length int) {
+	if length < 0 {
+		panic("Invalid bus length")
+	}
+	if length > len(b.data) {
+		valid := make([]bool, length)
+		dirty := make([]bool, length)
+		data := make([]byte, length)
+		copy(valid, b.valid)
+		copy(dirty, b.dirty)
+		copy(data, b.data)
+		b.valid, b.dirty, b.data = valid, dirty, data
+	}
+	b.length = length
+}
+
 func (b *Bus) IsValid(offset int) bool {
 	return b.valid[offset]
 }
@@ -42,22 +42,49 @@ func NewCache(size, lineWidth, busWidth, offsetBits int, lower flamego.Store) *C
 }
 
 type Cache struct {
-	size           int
-	lineWidth      int
-	lineCount      int
-	lines          []*CacheLine
-	bus            *Bus
-	tagBits        int
-	indexBits      int
-	offsetBits     int
-	isSuccessful   bool
-	isBusy         bool
-	isFree         bool
-	address        uint64
-	operation      flamego.CacheOperation
-	lower          flamego.Store
-	lowerAddress   uint64
-	lowerOperation flamego.CacheOperation
+	size             int
+	lineWidth        int
+	lineCount        int
+	lines            []*CacheLine
+	bus              *Bus
+	tagBits          int
+	indexBits        int
+	offsetBits       int
+	isSuccessful     bool
+	isBusy           bool
+	isFree           bool
+	address          uint64
+	operation        flamego.CacheOperation
+	lower            flamego.Store
+	lowerAddress     uint64
+	lowerOperation   flamego.CacheOperation
+	statistics       CacheStatistics
+	burst            Burst
+	remaining        int // Clocks before the current request completes
+	prefetcher       Prefetcher
+	prefetchAddress  uint64 // Address to prefetch once the lower store is idle
+	prefetchLength   int
+	hasPrefetch      bool
+	isPrefetching    bool // Whether the read from the lower store is a prefetch
+	isPrefetchIssued bool
+	lastAddress      uint64
+	stride           int64
+	writePolicy      WritePolicy
+	writes           []writeRegister // Writes held until they are written to the lower store, in order of issue
+	isDraining       bool            // Whether the write to the lower store is held in the write buffer
+}
+
+// CacheStatistics counts the reads served by a cache.
+type CacheStatistics struct {
+	Hits   uint64 // Reads served from the cache
+	Misses uint64 // Reads requested from the lower store
+
+	Prefetches uint64 // Speculative reads requested from the lower store
+	Useful     uint64 // Prefetched lines which were read
+	Useless    uint64 // Prefetched lines which were repurposed before they were read
+
+	Buffered uint64 // Writes held to be written to the lower store
+	Stalled  uint64 // Writes retried as the write buffer was full
 }
 
 func (c *Cache) Size() int {
@@ -120,7 +147,25 @@ func (c *Cache) LowerOperation() flamego.CacheOperation {
 	return c.lowerOperation
 }
 
+func (c *Cache) Statistics() CacheStatistics {
+	return c.statistics
+}
+
+// SetBurst sets the timing of the burst transactions served by the cache, and whether the cache fills and writes back lines in bursts.
+func (c *Cache) SetBurst(burst Burst) {
+	c.burst = burst
+}
+
+func (c *Cache) Burst() Burst {
+	return c.burst
+}
+
 func (c *Cache) Clock(cycle int) {
+	if !c.isBusy && c.lowerOperation == flamego.CacheNone && !c.hasPrefetch && len(c.writes) == 0 {
+		// Idle
+		return
+	}
+	retrying := false
 	if c.lower.IsBusy() {
 		// Do nothing
 	} else {
@@ -128,35 +173,30 @@ func (c *Cache) Clock(cycle int) {
 		case flamego.CacheNone:
 			// Do nothing
 		case flamego.CacheRead:
-			if c.lower.IsSuccessful() {
+			if c.isPrefetching {
+				c.isPrefetching = false
+				if c.lower.IsSuccessful() {
+					c.prefetched()
+				} else if !c.hasPrefetch {
+					// Retry the prefetch, unless it was replaced
+					c.hasPrefetch = true
+					retrying = true
+				}
+			} else if c.lower.IsSuccessful() {
 				tag, index, offset := c.ParseAddress(c.lowerAddress)
 				line := c.lines[index]
 				if line.tag != tag {
-					writeback := false
-					start := 0
-					for i := 0; i < c.lineWidth; i++ {
-						if line.IsValid(i) && line.IsDirty(i) {
-							writeback = true
-							start = i
-							break
-						}
-					}
-					if writeback {
+					if start, length, writeback := c.victim(line); writeback {
 						// The cache line is valid and contains some dirty values
 						// The read from lower is now discarded :(
 						// the values in the bus will be overwritten with dirty values from line
 						// and then a write will be issued to lower.
 						// This may repeat until the cache line can be repurposed.
-
-						// align start to data boundary
-						for start%flamego.DataSize != 0 {
-							start--
-						}
-
 						victim := c.CreateAddress(line.tag, index, uint64(start))
 
 						lb := c.lower.Bus()
-						for i := 0; i < lb.Size() && start < c.lineWidth; i, start = i+1, start+1 {
+						lb.SetLength(length)
+						for i := 0; i < length && start < c.lineWidth; i, start = i+1, start+1 {
 							if line.IsValid(start) {
 								lb.Write(i, line.Read(start))
 							} else {
@@ -164,12 +204,11 @@ func (c *Cache) Clock(cycle int) {
 							}
 						}
 						// Issue a write
-						c.lowerAddress = victim
-						c.lowerOperation = flamego.CacheWrite
-						c.lower.Write(victim)
+						c.lowerRequest(flamego.CacheWrite, victim, length)
 						break // Don't free lower
 					} else {
 						// Writeback unnecessary, line can repurposed
+						c.evicted(line)
 						line.tag = tag
 						for i := 0; i < c.lineWidth; i++ {
 							line.SetValid(i, false)
@@ -177,25 +216,36 @@ func (c *Cache) Clock(cycle int) {
 					}
 				}
 				lb := c.lower.Bus()
-				// Copy from lower bus into cache line
-				for i, j := 0, int(offset); i < lb.Size() && j < c.lineWidth; i, j = i+1, j+1 {
-					if lb.IsValid(i) {
+				// Copy from lower bus into cache line, keeping values written since the read was issued
+				for i, j := 0, int(offset); i < lb.Length() && j < c.lineWidth; i, j = i+1, j+1 {
+					if lb.IsValid(i) && !(line.IsValid(j) && line.IsDirty(j)) {
 						line.Write(j, lb.Read(i))
 						line.SetDirty(j, false)
 					}
 				}
+				c.overlay(line, c.lowerAddress)
 			} else {
-				// If lower was unsuccessful it will get retried
+				// If lower was unsuccessful it will get retried, after the lower store is left for its other users for a cycle
+				retrying = true
 			}
 			c.lower.Free()
 			c.lowerOperation = flamego.CacheNone
 		case flamego.CacheWrite:
+			if c.isDraining {
+				c.isDraining = false
+				if c.lower.IsSuccessful() {
+					c.writes = c.writes[1:]
+				} else {
+					// Retry the write, after the lower store is left for its other users for a cycle
+					retrying = true
+				}
+			}
 			if c.lower.IsSuccessful() {
 				tag, index, offset := c.ParseAddress(c.lowerAddress)
 				line := c.lines[index]
 				if line.tag == tag {
 					lb := c.lower.Bus()
-					for i, j := 0, int(offset); i < lb.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+					for i, j := 0, int(offset); i < lb.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 						if line.IsValid(j) && lb.IsValid(i) && line.Read(j) == lb.Read(i) {
 							line.SetDirty(j, false)
 						}
@@ -211,7 +261,10 @@ func (c *Cache) Clock(cycle int) {
 		}
 	}
 
-	if c.isBusy {
+	if c.isBusy && c.remaining > 1 && c.isHit() {
+		// Burst is still being transferred
+		c.remaining--
+	} else if c.isBusy {
 		tag, index, offset := c.ParseAddress(c.address)
 
 		line := c.lines[index]
@@ -222,24 +275,54 @@ func (c *Cache) Clock(cycle int) {
 			// Do nothing
 		case flamego.CacheRead:
 			// Check all values are valid
-			for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+			for i, j := 0, int(offset); i < c.bus.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 				if !line.IsValid(j) {
 					c.isSuccessful = false
 				}
 			}
 			if c.isSuccessful {
 				// Copy values into bus
-				for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+				for i, j := 0, int(offset); i < c.bus.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 					c.bus.Write(i, line.Read(j))
 					c.bus.SetDirty(i, false)
 				}
+				c.statistics.Hits++
+				prefetched := line.isPrefetched
+				if prefetched {
+					c.statistics.Useful++
+					line.isPrefetched = false
+				}
+				c.train(c.address, true, prefetched)
 			} else {
 				// Issue read request to lower store
-				c.lowerRead(c.address)
+				c.miss(c.address)
+				c.train(c.address, false, false)
 			}
 		case flamego.CacheWrite:
+			if !c.isSuccessful && !c.writePolicy.NoAllocate {
+				if start, length, writeback := c.victim(line); writeback {
+					victim := c.CreateAddress(line.tag, index, uint64(start))
+
+					// Write back to lower
+					c.lowerWrite(victim, line, start, length)
+					break
+				}
+				// Writeback unnecessary, line can repurposed
+				c.evicted(line)
+				line.tag = tag
+				c.isSuccessful = true
+				for i := 0; i < c.lineWidth; i++ {
+					line.SetValid(i, false)
+					line.SetDirty(i, false)
+				}
+			}
+			if (c.writePolicy.Through || !c.isSuccessful) && !c.write(c.address) {
+				// Write buffer is full
+				c.isSuccessful = false
+				break
+			}
 			if c.isSuccessful {
-				for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+				for i, j := 0, int(offset); i < c.bus.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 					if !c.bus.IsValid(i) || !c.bus.IsDirty(i) {
 						continue
 					}
@@ -247,45 +330,12 @@ func (c *Cache) Clock(cycle int) {
 					line.Write(j, c.bus.Read(i))
 				}
 			} else {
-				writeback := false
-				start := 0
-				for i := 0; i < c.lineWidth; i++ {
-					if line.IsValid(i) && line.IsDirty(i) {
-						writeback = true
-						start = i
-						break
-					}
-				}
-				if writeback {
-					// align start to data boundary
-					for start%flamego.DataSize != 0 {
-						start--
-					}
-
-					victim := c.CreateAddress(line.tag, index, uint64(start))
-
-					// Write back to lower
-					c.lowerWrite(victim, line, start)
-				} else {
-					// Writeback unnecessary, line can repurposed
-					line.tag = tag
-					c.isSuccessful = true
-					for i := 0; i < c.lineWidth; i++ {
-						line.SetValid(i, false)
-						line.SetDirty(i, false)
-					}
-					for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
-						if !c.bus.IsValid(i) || !c.bus.IsDirty(i) {
-							continue
-						}
-						line.Write(j, c.bus.Read(i))
-						c.bus.SetDirty(i, false)
-					}
-				}
+				// Write is held without allocating a line
+				c.isSuccessful = true
 			}
 		case flamego.CacheClear:
 			if c.isSuccessful {
-				for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+				for i, j := 0, int(offset); i < c.bus.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 					line.SetValid(j, false)
 				}
 			}
@@ -294,7 +344,7 @@ func (c *Cache) Clock(cycle int) {
 			if c.isSuccessful {
 				// Only flush if any of the data is dirty
 				c.isSuccessful = false
-				for i, j := 0, int(offset); i < c.bus.Size() && j < c.lineWidth; i, j = i+1, j+1 {
+				for i, j := 0, int(offset); i < c.bus.Length() && j < c.lineWidth; i, j = i+1, j+1 {
 					if line.IsValid(j) && line.IsDirty(j) {
 						c.isSuccessful = true
 					}
@@ -302,22 +352,60 @@ func (c *Cache) Clock(cycle int) {
 			}
 			if c.isSuccessful {
 				// Issue write request to lower store
-				c.lowerWrite(c.address, line, int(offset))
+				c.lowerWrite(c.address, line, int(offset), c.lower.Bus().Size())
 				// Cache still contains dirty data until the lower store write is successful
 				c.isSuccessful = false
 			} else {
 				// Cache doesn't contain the data for the given address, so nothing to flush, success!
 				c.isSuccessful = true
 			}
+			if len(c.writes) > 0 {
+				// Held writes are written before the flush succeeds
+				c.isSuccessful = false
+			}
 		default:
 			panic(fmt.Errorf("Unrecognized Cache Operation: %v", c.operation))
 		}
 		c.isBusy = false
 		c.operation = flamego.CacheNone
 	}
+
+	if c.lowerOperation == flamego.CacheNone && !retrying {
+		if len(c.writes) > 0 {
+			// Write the oldest held write before any miss is read, so the lower store never returns values older than those written
+			c.lowerWriteBuffered()
+		} else if c.hasPrefetch {
+			// Issue the prefetch once there are no outstanding misses
+			c.issuePrefetch()
+		}
+	}
 }
 
 func (c *Cache) Read(address uint64) {
+	c.request(flamego.CacheRead, address, c.bus.Size())
+}
+
+func (c *Cache) Write(address uint64) {
+	c.request(flamego.CacheWrite, address, c.bus.Size())
+}
+
+func (c *Cache) ReadBurst(address uint64, length int) {
+	c.request(flamego.CacheRead, address, length)
+}
+
+func (c *Cache) WriteBurst(address uint64, length int) {
+	c.request(flamego.CacheWrite, address, length)
+}
+
+func (c *Cache) Clear(address uint64) {
+	c.request(flamego.CacheClear, address, c.bus.Size())
+}
+
+func (c *Cache) Flush(address uint64) {
+	c.request(flamego.CacheFlush, address, c.bus.Size())
+}
+
+func (c *Cache) request(operation flamego.CacheOperation, address uint64, length int) {
 	if address < 0 {
 		panic("Cache access error")
 	}
@@ -327,73 +415,125 @@ func (c *Cache) Read(address uint64) {
 	c.isSuccessful = false
 	c.isBusy = true
 	c.isFree = false
-	c.operation = flamego.CacheRead
+	c.operation = operation
 	c.address = address
+	c.remaining = 1
+	if length > c.bus.Size() {
+		c.remaining = c.burst.Clocks(length, c.bus.Size())
+	}
+	c.bus.SetLength(length)
 }
 
-func (c *Cache) Write(address uint64) {
-	if address < 0 {
-		panic("Cache access error")
+// isHit returns true if the current request is a read or write of a line held by the cache.
+func (c *Cache) isHit() bool {
+	tag, index, offset := c.ParseAddress(c.address)
+	line := c.lines[index]
+	if line.tag != tag {
+		return false
 	}
-	if c.isBusy {
-		panic("Cache already busy")
+	switch c.operation {
+	case flamego.CacheRead:
+		for j, end := int(offset), int(offset)+c.bus.Length(); j < end && j < c.lineWidth; j++ {
+			if !line.IsValid(j) {
+				return false
+			}
+		}
+		return true
+	case flamego.CacheWrite:
+		return true
 	}
-	c.isSuccessful = false
-	c.isBusy = true
-	c.isFree = false
-	c.operation = flamego.CacheWrite
-	c.address = address
+	return false
 }
 
-func (c *Cache) Clear(address uint64) {
-	if address < 0 {
-		panic("Cache access error")
+// victim returns the offset and length of the values of the line to write back, and whether any value is dirty.
+// The values start from the first dirty value, and span the bus, or in a burst, up to the last dirty value.
+func (c *Cache) victim(line *CacheLine) (int, int, bool) {
+	start, end := -1, 0
+	for i := 0; i < c.lineWidth; i++ {
+		if line.IsValid(i) && line.IsDirty(i) {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		}
 	}
-	if c.isBusy {
-		panic("Cache already busy")
+	if start < 0 {
+		return 0, 0, false
 	}
-	c.isSuccessful = false
-	c.isBusy = true
-	c.isFree = false
-	c.operation = flamego.CacheClear
-	c.address = address
+	// align start to data boundary
+	for start%flamego.DataSize != 0 {
+		start--
+	}
+	length := c.lower.Bus().Size()
+	if c.burst.IsEnabled() {
+		for end%flamego.DataSize != 0 && end < c.lineWidth {
+			end++
+		}
+		if end-start > length {
+			length = end - start
+		}
+	}
+	return start, length, true
 }
 
-func (c *Cache) Flush(address uint64) {
-	if address < 0 {
-		panic("Cache access error")
+// unit returns the number of bytes filled by a read from the lower store for the current request.
+// In a burst the cache fills the span requested by an upper cache, or the whole line if requested by a context,
+// otherwise it fills a transfer of the bus of the lower store.
+func (c *Cache) unit() int {
+	if !c.burst.IsEnabled() {
+		return c.lower.Bus().Size()
 	}
-	if c.isBusy {
-		panic("Cache already busy")
+	if length := c.bus.Length(); length > c.bus.Size() && length < c.lineWidth {
+		return length
 	}
-	c.isSuccessful = false
-	c.isBusy = true
-	c.isFree = false
-	c.operation = flamego.CacheFlush
-	c.address = address
+	return c.lineWidth
 }
 
-func (c *Cache) lowerRead(address uint64) {
+// miss requests the address from the lower store, the reader retries until the line is filled.
+func (c *Cache) miss(address uint64) {
+	c.statistics.Misses++
+	length := c.unit()
+	if c.burst.IsEnabled() {
+		address -= address % uint64(length)
+	}
+	c.lowerRead(address, length)
+}
+
+func (c *Cache) lowerRead(address uint64, length int) {
 	if !c.lower.IsBusy() && c.lower.IsFree() {
-		c.lowerAddress = address
-		c.lowerOperation = flamego.CacheRead
-		c.lower.Read(address)
+		c.lowerRequest(flamego.CacheRead, address, length)
 	}
 }
 
-func (c *Cache) lowerWrite(address uint64, line *CacheLine, offset int) {
+func (c *Cache) lowerWrite(address uint64, line *CacheLine, offset, length int) {
 	if !c.lower.IsBusy() && c.lower.IsFree() {
-		c.lowerAddress = address
-		c.lowerOperation = flamego.CacheWrite
 		// Copy values into bus
 		lb := c.lower.Bus()
-		for i := 0; i < lb.Size() && offset < c.lineWidth; i, offset = i+1, offset+1 {
+		lb.SetLength(length)
+		for i := 0; i < length && offset < c.lineWidth; i, offset = i+1, offset+1 {
 			if line.IsValid(offset) && line.IsDirty(offset) {
 				lb.Write(i, line.Read(offset))
 			} else {
 				lb.SetValid(i, false)
 			}
 		}
+		c.lowerRequest(flamego.CacheWrite, address, length)
+	}
+}
+
+// lowerRequest issues the operation to the lower store, in a burst if the length is more than the size of its bus.
+func (c *Cache) lowerRequest(operation flamego.CacheOperation, address uint64, length int) {
+	c.lowerAddress = address
+	c.lowerOperation = operation
+	burst := length > c.lower.Bus().Size()
+	switch {
+	case operation == flamego.CacheRead && burst:
+		c.lower.ReadBurst(address, length)
+	case operation == flamego.CacheRead:
+		c.lower.Read(address)
+	case burst:
+		c.lower.WriteBurst(address, length)
+	default:
 		c.lower.Write(address)
 	}
 }
@@ -416,16 +556,18 @@ func (c *Cache) CreateAddress(tag, index, offset uint64) uint64 {
 
 type CacheLine struct {
 	Bus
-	tag uint64
+	tag          uint64
+	isPrefetched bool // Whether the line was filled by a prefetch, and hasn't been read since
 }
 
 func NewCacheLine(size int) *CacheLine {
 	return &CacheLine{
 		Bus: Bus{
-			size:  size,
-			valid: make([]bool, size),
-			dirty: make([]bool, size),
-			data:  make([]byte, size),
+			size:   size,
+			length: size,
+			valid:  make([]bool, size),
+			dirty:  make([]bool, size),
+			data:   make([]byte, size),
 		},
 	}
 }
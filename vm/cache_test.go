@@ -3,7 +3,9 @@ package vm_test
 import (
 	"aletheiaware.com/flamego"
 	"aletheiaware.com/flamego/vm"
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -245,7 +247,7 @@ func TestCache_Flush(t *testing.T) {
 
 	// Ensure data was written to memory
 	memory.Clock(0)
-	d := memory.Data()
+	d := memory.Get(0, len(data))
 	for i, b := range data {
 		assert.Equal(t, b, d[i])
 	}
@@ -486,3 +488,287 @@ func assertCacheFlushMiss(t *testing.T, cache *vm.Cache, address uint64) {
 	assert.False(t, cache.IsFree())       // Cache has not yet been released
 	assert.False(t, cache.IsSuccessful()) // Data is still being flushed
 }
+
+func TestCache_Burst(t *testing.T) {
+	// Lines are wider than the bus of memory
+	lineWidth, offsetBits := 16, 4
+	address := uint64(8)
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	memory := vm.NewMemory(MemorySize)
+	memory.Set(0, data)
+	memory.SetBurst(vm.Burst{Latency: 1, Bandwidth: 8})
+
+	cache := vm.NewCache(CacheSize, lineWidth, BusSize, offsetBits, memory)
+	cache.SetBurst(vm.Burst{Bandwidth: 8})
+
+	assertCacheReadMiss(t, cache, address)
+
+	// Whole line is requested in a single burst
+	assert.Equal(t, uint64(0), cache.LowerAddress())
+	assert.Equal(t, flamego.CacheRead, cache.LowerOperation())
+	assert.Equal(t, uint64(0), memory.Address())
+	assert.Equal(t, lineWidth, memory.Bus().Length())
+
+	// Latency, then a clock per 8 bytes
+	memory.Clock(0)
+	memory.Clock(0)
+	assert.True(t, memory.IsBusy())
+	memory.Clock(0)
+	assert.False(t, memory.IsBusy())
+	assert.True(t, memory.IsSuccessful())
+
+	cache.Clock(0)
+	assert.True(t, memory.IsFree())
+
+	for a := uint64(0); a < uint64(lineWidth); a += BusSize {
+		assertCacheReadHit(t, cache, a, data[a:a+BusSize])
+	}
+}
+
+func TestCache_Burst_Writeback(t *testing.T) {
+	lineWidth, offsetBits := 16, 4
+	memory := vm.NewMemory(MemorySize)
+
+	cache := vm.NewCache(CacheSize, lineWidth, BusSize, offsetBits, memory)
+	cache.SetBurst(vm.Burst{Bandwidth: 8})
+	assertCacheWriteHit(t, cache, 0, []byte{0, 1, 2, 3})
+	assertCacheWriteHit(t, cache, 8, []byte{8, 9, 10, 11})
+
+	// Another line with the same index misses
+	assertCacheReadMiss(t, cache, CacheSize)
+	assert.Equal(t, uint64(CacheSize), memory.Address())
+	for memory.IsBusy() {
+		memory.Clock(0)
+	}
+	cache.Clock(0)
+
+	// Dirty values are written back in a single burst
+	assert.Equal(t, lineWidth, memory.Bus().Length())
+	assert.Equal(t, uint64(0), cache.LowerAddress())
+	assert.Equal(t, flamego.CacheWrite, cache.LowerOperation())
+	for memory.IsBusy() {
+		memory.Clock(0)
+	}
+	cache.Clock(0)
+	assert.True(t, memory.IsFree())
+	assert.Equal(t, []byte{0, 1, 2, 3, 0, 0, 0, 0, 8, 9, 10, 11, 0, 0, 0, 0}, memory.Get(0, lineWidth))
+}
+
+func TestCache_Burst_Samples(t *testing.T) {
+	singles, bursts := assertSameAsBarrel(t, vm.Config{Burst: vm.Burst{Bandwidth: 64}}, loadSamples(t, samples...))
+	for name, m := range bursts {
+		// Lines are filled with fewer transfers from memory
+		assert.Less(t, m.Tick, singles[name].Tick, name)
+	}
+}
+
+func TestCache_Prefetch_NextLine(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	memory.Set(0, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetPrefetcher(vm.PrefetchNextLine)
+
+	assertCacheReadMiss(t, cache, 0)
+	assertLowerRead(t, cache, memory, 0)
+	cache.Clock(0)
+
+	// Following line is read once the lower store is idle
+	assert.Equal(t, uint64(LineWidth), cache.LowerAddress())
+	assert.Equal(t, flamego.CacheRead, cache.LowerOperation())
+	memory.Clock(0)
+	cache.Clock(0)
+	assert.Equal(t, flamego.CacheNone, cache.LowerOperation())
+
+	assertCacheReadHit(t, cache, 8, []byte{8, 9, 10, 11})
+	assertCacheReadHit(t, cache, 12, []byte{12, 13, 14, 15})
+
+	// Reading the prefetched line prefetches the line after
+	assert.Equal(t, uint64(2*LineWidth), cache.LowerAddress())
+
+	s := cache.Statistics()
+	assert.Equal(t, uint64(1), s.Misses)
+	assert.Equal(t, uint64(2), s.Prefetches)
+	assert.Equal(t, uint64(1), s.Useful)
+	assert.Equal(t, uint64(0), s.Useless)
+}
+
+func TestCache_Prefetch_Stride(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	memory.Set(48, []byte{48, 49, 50, 51})
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetPrefetcher(vm.PrefetchStride)
+
+	for _, a := range []uint64{0, 16} {
+		assertCacheReadMiss(t, cache, a)
+		assertLowerRead(t, cache, memory, a)
+		cache.Clock(0)
+		assert.Equal(t, flamego.CacheNone, cache.LowerOperation())
+		assertCacheReadHit(t, cache, a, []byte{0, 0, 0, 0})
+	}
+
+	// Same stride is seen again, so the line a stride ahead is read
+	assertCacheReadMiss(t, cache, 32)
+	assertLowerRead(t, cache, memory, 32)
+	cache.Clock(0)
+	assert.Equal(t, uint64(48), cache.LowerAddress())
+	memory.Clock(0)
+	cache.Clock(0)
+
+	assertCacheReadHit(t, cache, 48, []byte{48, 49, 50, 51})
+
+	s := cache.Statistics()
+	assert.Equal(t, uint64(3), s.Misses)
+	assert.Equal(t, uint64(2), s.Prefetches) // Reading 48 prefetches 64
+	assert.Equal(t, uint64(1), s.Useful)
+}
+
+func TestCache_Prefetch_Useless(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetPrefetcher(vm.PrefetchNextLine)
+
+	assertCacheReadMiss(t, cache, 0)
+	assertLowerRead(t, cache, memory, 0)
+	cache.Clock(0)
+	memory.Clock(0)
+	cache.Clock(0)
+
+	// Line with the same index as the prefetched line repurposes it before it is read
+	cache.SetPrefetcher(vm.PrefetchNone)
+	assertCacheReadMiss(t, cache, CacheSize+LineWidth)
+	assertLowerRead(t, cache, memory, CacheSize+LineWidth)
+	cache.Clock(0)
+
+	s := cache.Statistics()
+	assert.Equal(t, uint64(1), s.Prefetches)
+	assert.Equal(t, uint64(0), s.Useful)
+	assert.Equal(t, uint64(1), s.Useless)
+}
+
+func TestCache_WriteThrough(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetWritePolicy(vm.WritePolicy{Through: true})
+	assertCacheWriteHit(t, cache, 0, []byte{0, 1, 2, 3})
+
+	// Write is written to the lower store as well as the line
+	assert.Equal(t, []uint64{0}, cache.Writes())
+	assert.Equal(t, uint64(0), cache.LowerAddress())
+	assert.Equal(t, flamego.CacheWrite, cache.LowerOperation())
+
+	// Another write is retried while the first is being written
+	cache.Bus().Write(0, 4)
+	cache.Write(4)
+	cache.Clock(0)
+	assert.False(t, cache.IsSuccessful())
+	cache.Free()
+
+	memory.Clock(0)
+	cache.Clock(0)
+	assert.Empty(t, cache.Writes())
+	assert.Equal(t, []byte{0, 1, 2, 3}, memory.Get(0, 4))
+
+	assertCacheWriteHit(t, cache, 4, []byte{4, 5, 6, 7})
+	assertCacheReadHit(t, cache, 0, []byte{0, 1, 2, 3})
+	assertCacheReadHit(t, cache, 4, []byte{4, 5, 6, 7})
+
+	s := cache.Statistics()
+	assert.Equal(t, uint64(2), s.Buffered)
+	assert.Equal(t, uint64(1), s.Stalled)
+}
+
+func TestCache_WriteThrough_Buffer(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetWritePolicy(vm.WritePolicy{Through: true, Buffer: 2})
+	assertCacheWriteHit(t, cache, 0, []byte{0, 1, 2, 3})
+	assertCacheWriteHit(t, cache, 4, []byte{4, 5, 6, 7})
+	assert.Equal(t, []uint64{0, 4}, cache.Writes())
+
+	// Writes are written in order
+	for _, a := range []uint64{0, 4} {
+		assert.Equal(t, a, cache.LowerAddress())
+		for memory.IsBusy() {
+			memory.Clock(0)
+		}
+		cache.Clock(0)
+	}
+	assert.Empty(t, cache.Writes())
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7}, memory.Get(0, 8))
+
+	// Values written through are clean
+	assertCacheFlushHit(t, cache, 0)
+}
+
+func TestCache_NoAllocate(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	cache.SetWritePolicy(vm.WritePolicy{NoAllocate: true, Buffer: 1})
+	bus := cache.Bus()
+	for i, b := range []byte{0, 1, 2, 3} {
+		bus.Write(i, b)
+	}
+	cache.Write(CacheSize)
+	cache.Clock(0)
+	assert.True(t, cache.IsSuccessful())
+	cache.Free()
+
+	// Write is written to the lower store without allocating a line
+	assert.Equal(t, flamego.CacheWrite, cache.LowerOperation())
+	memory.Clock(0)
+	cache.Clock(0)
+	assert.Equal(t, []byte{0, 1, 2, 3}, memory.Get(CacheSize, 4))
+	assertCacheReadMiss(t, cache, CacheSize)
+}
+
+func TestCache_WritePolicy_Machine(t *testing.T) {
+	for name, test := range map[string]struct {
+		policy  vm.WritePolicy
+		visible bool
+	}{
+		"back":               {},
+		"through":            {vm.WritePolicy{Through: true}, true},
+		"through+noallocate": {vm.WritePolicy{Through: true, NoAllocate: true, Buffer: 4}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Value is stored without a flush
+			m := runMachine(t, vm.Config{
+				Write: [3]vm.WritePolicy{test.policy, test.policy, test.policy},
+			}, strings.NewReader("store r0 #test r1\n"+strings.Repeat("noop\n", 20)+"halt\n#test\ndata 0\n"))
+			for i := 0; i < 10000; i++ {
+				m.Clock()
+			}
+			value := binary.BigEndian.Uint64(m.Memory.Get(22*flamego.InstructionSize, flamego.DataSize))
+			if test.visible {
+				assert.Equal(t, uint64(1), value)
+			} else {
+				assert.Equal(t, uint64(0), value)
+			}
+		})
+	}
+}
+
+func TestCache_WritePolicy_Samples(t *testing.T) {
+	through := vm.WritePolicy{Through: true}
+	// Writes are retried while the previous write is written
+	backs, throughs := assertSameAsBarrel(t, vm.Config{Write: [3]vm.WritePolicy{through, through, through}}, loadSamples(t, samples...))
+	for name, writes := range map[string]uint64{
+		"add":           0,
+		"call":          5, // 4 registers and the return address pushed
+		"loop":          0,
+		"mailbox":       0,
+		"saverestore":   28, // R4 - R31 saved
+		"storeandflush": 1,
+	} {
+		// Each write reaches the L3 cache once, instead of staying in the L1 cache
+		assert.Equal(t, writes, throughs[name].Processor.Cache().(*vm.Cache).Statistics().Buffered, name)
+		assert.Zero(t, backs[name].Processor.Cache().(*vm.Cache).Statistics().Buffered, name)
+	}
+}
@@ -0,0 +1,56 @@
+package vm
+
+import (
+	"fmt"
+)
+
+// CoreModel selects the implementation of each core.
+type CoreModel int
+
+const (
+	// Barrel cores interleave 8 contexts through an 8-stage pipeline, so there are no hazards
+	CoreBarrel CoreModel = iota
+	// Pipelined cores run a single context through a classic 5-stage pipeline, with forwarding, stalls, and branch prediction
+	CorePipelined
+)
+
+func (m CoreModel) String() string {
+	switch m {
+	case CoreBarrel:
+		return "barrel"
+	case CorePipelined:
+		return "pipelined"
+	}
+	return "unknown"
+}
+
+func ParseCoreModel(s string) (CoreModel, error) {
+	switch s {
+	case "barrel":
+		return CoreBarrel, nil
+	case "pipelined":
+		return CorePipelined, nil
+	}
+	return 0, fmt.Errorf("Unrecognized Core Model: %s", s)
+}
+
+// Config describes the components of a machine, the zero value is the default machine.
+type Config struct {
+	Core CoreModel
+	// Issue selects the context issuing each cycle in barrel cores
+	Issue IssuePolicy
+	// Weights of the contexts of each barrel core under IssuePriority, by context index; contexts without a weight have weight 1
+	Weights []int
+	// Burst is the timing of burst transactions of memory and each cache, caches fill and write back whole lines in bursts when its Bandwidth is non-zero
+	Burst Burst
+	// Prefetch selects the prefetcher of each cache
+	Prefetch Prefetcher
+	// Write is the write policy of the L1, L2, and L3 caches, in that order
+	Write [3]WritePolicy
+	// MemorySize is the size of memory in bytes, zero for flamego.SizeMemory
+	MemorySize uint64
+	// Arbitration selects the port of the memory controller served next when the L3 cache and devices are waiting
+	Arbitration Arbitration
+	// Parallel clocks each core on its own goroutine
+	Parallel bool
+}
@@ -4,10 +4,11 @@ import (
 	"aletheiaware.com/flamego"
 	"aletheiaware.com/flamego/isa"
 	"encoding/binary"
+	"log"
 )
 
-func NewContext(id int, c *Core, l1ICache flamego.Cache, l1DCache flamego.Cache) *Context {
-	return &Context{
+func NewContext(id int, c flamego.Core, l1ICache flamego.Cache, l1DCache flamego.Cache) *Context {
+	x := &Context{
 		id:            id,
 		core:          c,
 		iCache:        l1ICache,
@@ -15,31 +16,45 @@ func NewContext(id int, c *Core, l1ICache flamego.Cache, l1DCache flamego.Cache)
 		status:        "asleep",
 		isAsleep:      true,
 		nextInterrupt: -1,
+		parent:        flamego.InterruptSourceHost,
 	}
+	if d, ok := c.(decoder); ok {
+		// Share decoded instructions with the other contexts of the core
+		x.decodes = d.DecodeCache()
+	} else {
+		x.decodes = &isa.DecodeCache{}
+	}
+	return x
 }
 
 type Context struct {
 	id        int
-	core      *Core
+	core      flamego.Core
+	decodes   *isa.DecodeCache
 	iCache    flamego.Cache
 	dCache    flamego.Cache
+	memoryMap *MemoryMap   // Checked by instruction fetches, if any
+	mapped    *mappedCache // Data cache checked against the memory map, if any
 	registers [flamego.RegisterCount]uint64
 
 	status        string
 	isValid       bool
 	isAsleep      bool
+	isWaiting     bool
 	sleepCycles   int
 	isInterrupted bool
 	nextInterrupt flamego.InterruptValue
 	isSignalled   bool
 	isRetrying    bool
 	isAligned     bool
-	requiresLock  bool
-	acquiredLock  bool
+	requiresLocks uint64 // Bit set of locks required, one bit per lock
+	acquiredLocks uint64 // Bit set of locks acquired, one bit per lock
+	retired       uint64
+	parent        int
 
-	opcode            uint32
-	instruction       flamego.Instruction
-	instructionString string
+	opcode      uint32
+	instruction flamego.Instruction
+	state       flamego.InstructionState
 }
 
 func (x *Context) Id() int {
@@ -55,9 +70,35 @@ func (x *Context) InstructionCache() flamego.Cache {
 }
 
 func (x *Context) DataCache() flamego.Cache {
+	if x.mapped != nil {
+		return x.mapped
+	}
 	return x.dCache
 }
 
+// SetMemoryMap checks the fetches, reads, and writes of the context against the memory map, so faults raise InterruptMemoryAccessError and MMIO regions are served by their devices.
+func (x *Context) SetMemoryMap(m *MemoryMap) {
+	x.memoryMap = m
+	if m == nil {
+		x.mapped = nil
+		return
+	}
+	x.mapped = &mappedCache{
+		Cache:     x.dCache,
+		context:   x,
+		memoryMap: m,
+	}
+}
+
+// isExecutable returns true if the instruction at the address can be fetched, instructions are only fetched from RAM and ROM regions of the memory map.
+func (x *Context) isExecutable(address uint64) bool {
+	if x.memoryMap == nil {
+		return true
+	}
+	r, ok := x.memoryMap.Region(address, flamego.InstructionSize)
+	return ok && r.Type != RegionMMIO
+}
+
 func (x *Context) Status() string {
 	return x.status
 }
@@ -75,6 +116,56 @@ func (x *Context) Sleep() {
 	x.status = "asleep"
 }
 
+// IsWaiting reports whether the context is asleep waiting for a message.
+func (x *Context) IsWaiting() bool {
+	return x.isWaiting
+}
+
+// Wait puts the context to sleep until it is woken by a message, or a signal.
+func (x *Context) Wait() {
+	x.isAsleep = true
+	x.isWaiting = true
+	x.status = "waiting"
+}
+
+// Wake a context waiting for a message, so the waiting instruction is fetched again.
+func (x *Context) Wake() {
+	if x.isWaiting {
+		x.isAsleep = false
+		x.isWaiting = false
+		x.isValid = false // Discard the instruction in flight
+		x.sleepCycles = 0
+		x.status = "woken"
+	}
+}
+
+// Spawn starts an idle context outside of an interrupt, loading the given registers from R4 onwards and clearing the general purpose registers.
+// Returns false if the context is awake, waiting for a message, or signalled.
+func (x *Context) Spawn(parent int, registers []uint64) bool {
+	if !x.isAsleep || x.isWaiting || x.isSignalled {
+		return false
+	}
+	for r := flamego.R4; r < flamego.RegisterCount; r++ {
+		x.registers[r] = 0
+	}
+	for i, v := range registers {
+		x.registers[flamego.R4+flamego.Register(i)] = v
+	}
+	x.parent = parent
+	x.isAsleep = false
+	x.isInterrupted = false
+	x.nextInterrupt = -1
+	x.isValid = false // Discard the instruction in flight
+	x.sleepCycles = 0
+	x.status = "spawned"
+	return true
+}
+
+// Parent returns the context that spawned this context, or InterruptSourceHost if it was not spawned.
+func (x *Context) Parent() int {
+	return x.parent
+}
+
 func (x *Context) SleepCycles() int {
 	return x.sleepCycles
 }
@@ -91,9 +182,14 @@ func (x *Context) SetInterrupted(i bool) {
 	x.isInterrupted = i
 }
 
+// Error raises the interrupt, which is taken in place of the next instruction.
+// An error raised while interrupted can't be serviced, so the processor halts.
 func (x *Context) Error(value flamego.InterruptValue) {
 	if x.isInterrupted {
-		panic("Double Interrupt")
+		log.Println("Double Interrupt:", value, "Context:", flamego.ContextIdentifier(x))
+		x.status = "double interrupt"
+		x.core.Processor().Halt()
+		return
 	}
 	x.nextInterrupt = value
 	x.status = "error"
@@ -124,23 +220,41 @@ func (x *Context) Instruction() flamego.Instruction {
 }
 
 func (x *Context) InstructionString() string {
-	return x.instructionString
+	if x.instruction == nil {
+		return "-"
+	}
+	return x.instruction.String()
 }
 
-func (x *Context) RequiresLock() bool {
-	return x.requiresLock
+// State returns the transient state of the instruction being executed.
+func (x *Context) State() *flamego.InstructionState {
+	return &x.state
 }
 
-func (x *Context) SetRequiresLock(required bool) {
-	x.requiresLock = required
+func (x *Context) RequiresLock(lock int) bool {
+	return x.requiresLocks&(1<<lock) != 0
 }
 
-func (x *Context) AcquiredLock() bool {
-	return x.acquiredLock
+func (x *Context) SetRequiresLock(lock int, required bool) {
+	if required {
+		x.requiresLocks |= 1 << lock
+	} else {
+		x.requiresLocks &^= 1 << lock
+	}
+	// Notify processor which arbitrates the lock
+	x.core.Processor().RequireLock(flamego.ContextIdentifier(x), lock, required)
 }
 
-func (x *Context) SetAcquiredLock(acquired bool) {
-	x.acquiredLock = acquired
+func (x *Context) AcquiredLock(lock int) bool {
+	return x.acquiredLocks&(1<<lock) != 0
+}
+
+func (x *Context) SetAcquiredLock(lock int, acquired bool) {
+	if acquired {
+		x.acquiredLocks |= 1 << lock
+	} else {
+		x.acquiredLocks &^= 1 << lock
+	}
 }
 
 func (x *Context) FetchInstruction() {
@@ -149,12 +263,8 @@ func (x *Context) FetchInstruction() {
 		x.status = "retrying instruction"
 	} else if x.nextInterrupt >= 0 {
 		x.status = "interrupted"
-	} else if !x.isInterrupted && !x.acquiredLock && x.isSignalled {
-		x.status = "signalled"
-		x.nextInterrupt = flamego.InterruptSignal
-		x.isAsleep = false
-		x.isSignalled = false
-		x.sleepCycles = 0
+	} else if x.isSignalPending() {
+		x.takeSignal()
 	} else if !x.isAsleep {
 		pc := x.ReadRegister(flamego.RProgramCounter)
 		if !x.isInterrupted {
@@ -170,6 +280,11 @@ func (x *Context) FetchInstruction() {
 			x.isValid = false
 			return
 		}
+		if !x.isExecutable(pc) {
+			x.Error(flamego.InterruptMemoryAccessError)
+			x.isValid = false
+			return
+		}
 		is := x.iCache
 		if is.IsBusy() || !is.IsFree() {
 			x.status = "cache busy"
@@ -190,6 +305,22 @@ func (x *Context) FetchInstruction() {
 	}
 }
 
+// isSignalPending returns true if the context is signalled, and able to take the interrupt.
+func (x *Context) isSignalPending() bool {
+	return !x.isInterrupted && x.acquiredLocks == 0 && x.isSignalled
+}
+
+// takeSignal wakes the context to start InterruptSignal.
+func (x *Context) takeSignal() {
+	x.status = "signalled"
+	x.nextInterrupt = flamego.InterruptSignal
+	x.isAsleep = false
+	x.isWaiting = false
+	// Claim the next interrupt, remaining signalled while more are pending
+	_, x.isSignalled = x.core.Processor().InterruptController().Claim(flamego.ContextIdentifier(x))
+	x.sleepCycles = 0
+}
+
 func (x *Context) LoadInstruction() {
 	if !x.isValid {
 		return
@@ -243,8 +374,8 @@ func (x *Context) DecodeInstruction() {
 		x.sleepCycles++
 		return
 	}
-	x.instruction = isa.Decode(x.opcode)
-	x.instructionString = x.instruction.String()
+	x.instruction = x.decodes.Decode(x.opcode)
+	x.state = flamego.InstructionState{}
 	x.status = "decoded instruction"
 }
 
@@ -308,17 +439,27 @@ func (x *Context) RetireInstruction() {
 		return
 	}
 	if x.instruction.Retire(x) {
+		x.retired++
 		x.opcode = 0
 		x.instruction = nil
-		x.instructionString = "-"
 		x.status = "retired instruction"
 		x.isRetrying = false
+	} else if x.nextInterrupt >= 0 {
+		// Instruction raised an error, so is abandoned for the interrupt, which returns to the instruction
+		x.opcode = 0
+		x.instruction = nil
+		x.status = "abandoned instruction"
+		x.isRetrying = false
 	} else {
 		x.status = "retrying instruction"
 		x.isRetrying = true
 	}
 }
 
+func (x *Context) RetiredInstructions() uint64 {
+	return x.retired
+}
+
 func (x *Context) ReadRegister(register flamego.Register) uint64 {
 	if register < flamego.R0 || register > flamego.R31 {
 		x.Error(flamego.InterruptRegisterAccessError)
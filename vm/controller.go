@@ -0,0 +1,241 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"encoding/binary"
+	"log"
+	"sync"
+)
+
+const (
+	// Unrouted sources interrupt the target requested by the source
+	InterruptRouteNone = 0xffff
+
+	InterruptShiftPriority = 16
+	InterruptShiftMasked   = 24
+)
+
+var _ (flamego.InterruptController) = (*InterruptController)(nil)
+
+func NewInterruptController(m flamego.Memory, o uint64) *InterruptController {
+	c := &InterruptController{
+		Device:     *NewDevice(m, o),
+		routes:     make(map[int]int),
+		masked:     make(map[int]bool),
+		priorities: make(map[int]uint8),
+		held:       make(map[int][]int),
+		pending:    make(map[int][]*PendingInterrupt),
+		servicing:  make(map[int]int),
+	}
+	c.AddOperation(flamego.DeviceStatus, c.Status)
+	c.AddOperation(flamego.DeviceEnable, c.Enable)
+	c.AddOperation(flamego.DeviceDisable, c.Disable)
+	c.AddOperation(flamego.DeviceRead, c.ReadSource)
+	c.AddOperation(flamego.DeviceWrite, c.WriteSource)
+	c.OnStatus = c.describe
+	c.deviceType = flamego.DeviceTypeInterruptController
+	return c
+}
+
+type PendingInterrupt struct {
+	Source   int
+	Priority uint8
+}
+
+type InterruptController struct {
+	Device
+	routes      map[int]int
+	masked      map[int]bool
+	priorities  map[int]uint8
+	held        map[int][]int
+	pending     map[int][]*PendingInterrupt
+	servicing   map[int]int
+	mutex       sync.Mutex // Guards pending and servicing which are accessed by cores clocked in parallel
+	isRejected  bool       // Whether a configuration was rejected since the last Status
+	OnInterrupt func(int)
+}
+
+func (c *InterruptController) Route(source, context int) {
+	if context < 0 {
+		delete(c.routes, source)
+	} else {
+		c.routes[source] = context
+	}
+}
+
+// Routed returns the context interrupted by the source, or InterruptRouteNone if the source is unrouted.
+func (c *InterruptController) Routed(source int) int {
+	if r, ok := c.routes[source]; ok {
+		return r
+	}
+	return InterruptRouteNone
+}
+
+func (c *InterruptController) Mask(source int, masked bool) {
+	if masked {
+		c.masked[source] = true
+		return
+	}
+	delete(c.masked, source)
+	// Release held interrupts
+	targets := c.held[source]
+	delete(c.held, source)
+	for _, t := range targets {
+		c.Raise(source, t)
+	}
+}
+
+func (c *InterruptController) IsMasked(source int) bool {
+	return c.masked[source]
+}
+
+func (c *InterruptController) Priority(source int) uint8 {
+	return c.priorities[source]
+}
+
+func (c *InterruptController) SetPriority(source int, priority uint8) {
+	c.priorities[source] = priority
+}
+
+// Pending returns the interrupts waiting to be claimed by the given context.
+func (c *InterruptController) Pending(context int) []*PendingInterrupt {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.pending[context]
+}
+
+// Held returns the targets of interrupts from the given source waiting for the source to be unmasked.
+func (c *InterruptController) Held(source int) []int {
+	return c.held[source]
+}
+
+func (c *InterruptController) Raise(source, target int) {
+	if c.masked[source] {
+		c.held[source] = append(c.held[source], target)
+		return
+	}
+	if context, ok := c.routes[source]; ok {
+		target = context
+	}
+	c.mutex.Lock()
+	c.pending[target] = append(c.pending[target], &PendingInterrupt{
+		Source:   source,
+		Priority: c.priorities[source],
+	})
+	c.mutex.Unlock()
+	if f := c.OnInterrupt; f != nil {
+		f(target)
+	}
+}
+
+func (c *InterruptController) IsPending(context int) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.pending[context]) > 0
+}
+
+func (c *InterruptController) Claim(context int) (int, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pending := c.pending[context]
+	if len(pending) == 0 {
+		return flamego.InterruptSourceHost, false
+	}
+	// Find the earliest of the highest priority interrupts
+	index := 0
+	for i, p := range pending {
+		if p.Priority > pending[index].Priority {
+			index = i
+		}
+	}
+	source := pending[index].Source
+	c.pending[context] = append(pending[:index], pending[index+1:]...)
+	c.servicing[context] = source
+	return source, len(c.pending[context]) > 0
+}
+
+func (c *InterruptController) Acknowledge(context int) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	source, ok := c.servicing[context]
+	if !ok {
+		return flamego.InterruptSourceHost
+	}
+	delete(c.servicing, context)
+	return source
+}
+
+// describe reports a rejected configuration as unsupported.
+func (c *InterruptController) describe(s *flamego.DeviceDescriptor) {
+	if c.isRejected {
+		s.Error = flamego.DeviceErrorUnsupported
+	}
+	c.isRejected = false
+}
+
+func (c *InterruptController) Enable() error {
+	c.isBusy = false
+	c.operation = flamego.DeviceNone
+	c.SignalController()
+	return nil
+}
+
+func (c *InterruptController) Disable() error {
+	c.isBusy = false
+	c.operation = flamego.DeviceNone
+	return nil
+}
+
+// ReadSource writes the configuration of the source at DeviceAddress to MemoryAddress.
+func (c *InterruptController) ReadSource() error {
+	if !c.memory.IsBusy() && c.memory.IsFree() {
+		source := int(c.deviceAddress)
+		route := uint64(c.Routed(source))
+		config := route | uint64(c.priorities[source])<<InterruptShiftPriority
+		if c.masked[source] {
+			config |= 1 << InterruptShiftMasked
+		}
+		mb := c.memory.Bus()
+		buffer := make([]byte, flamego.DataSize)
+		binary.BigEndian.PutUint64(buffer, config)
+		for i, b := range buffer {
+			mb.Write(i, b)
+		}
+		c.memoryOperation = flamego.MemoryWrite
+		c.memory.Write(c.memoryAddress)
+		c.isBusy = false
+		c.operation = flamego.DeviceNone
+		c.SignalController()
+	}
+	return nil
+}
+
+// WriteSource configures the source at DeviceAddress from the Parameter;
+// 16bit route, 8bit priority, and 1bit mask.
+func (c *InterruptController) WriteSource() error {
+	source := int(c.deviceAddress)
+	route := int(c.parameter & 0xffff)
+	priority := uint8((c.parameter >> InterruptShiftPriority) & 0xff)
+	masked := (c.parameter>>InterruptShiftMasked)&0x1 == 0x1
+	log.Println("Interrupt Source:", source, "Route:", route, "Priority:", priority, "Masked:", masked)
+	switch {
+	case route == InterruptRouteNone:
+		c.Route(source, -1)
+	case route >= flamego.CoreCount*flamego.ContextCount:
+		// Only contexts can be interrupted
+		log.Println("Unrecognized Interrupt Route:", route)
+		c.isRejected = true
+		c.isBusy = false
+		c.operation = flamego.DeviceNone
+		c.SignalController()
+		return nil
+	default:
+		c.Route(source, route)
+	}
+	c.SetPriority(source, priority)
+	c.Mask(source, masked)
+	c.isBusy = false
+	c.operation = flamego.DeviceNone
+	c.SignalController()
+	return nil
+}
@@ -0,0 +1,141 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterruptController_Raise(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+	var interrupts []int
+	controller.OnInterrupt = func(c int) {
+		interrupts = append(interrupts, c)
+	}
+
+	// Interrupts are queued, not overwritten
+	controller.Raise(64, 3)
+	controller.Raise(65, 3)
+	assert.Equal(t, []int{3, 3}, interrupts)
+	assert.True(t, controller.IsPending(3))
+	assert.False(t, controller.IsPending(4))
+
+	source, more := controller.Claim(3)
+	assert.Equal(t, 64, source)
+	assert.True(t, more)
+
+	source, more = controller.Claim(3)
+	assert.Equal(t, 65, source)
+	assert.False(t, more)
+
+	assert.Equal(t, 65, controller.Acknowledge(3))
+	assert.Equal(t, flamego.InterruptSourceHost, controller.Acknowledge(3))
+}
+
+func TestInterruptController_Route(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+
+	controller.Route(64, 9)
+	controller.Raise(64, 0)
+	assert.False(t, controller.IsPending(0))
+	assert.True(t, controller.IsPending(9))
+
+	// Removing the route restores the requested target
+	controller.Route(64, -1)
+	controller.Raise(64, 0)
+	assert.True(t, controller.IsPending(0))
+}
+
+func TestInterruptController_Mask(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+
+	controller.Mask(64, true)
+	controller.Raise(64, 0)
+	assert.False(t, controller.IsPending(0))
+	assert.Equal(t, []int{0}, controller.Held(64))
+
+	// Unmasking releases held interrupts
+	controller.Mask(64, false)
+	assert.True(t, controller.IsPending(0))
+	assert.Empty(t, controller.Held(64))
+}
+
+func TestInterruptController_Priority(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+
+	controller.SetPriority(66, 2)
+	controller.SetPriority(65, 1)
+	controller.Raise(64, 0)
+	controller.Raise(65, 0)
+	controller.Raise(66, 0)
+	controller.Raise(67, 0)
+
+	var sources []int
+	for controller.IsPending(0) {
+		s, _ := controller.Claim(0)
+		sources = append(sources, s)
+	}
+	assert.Equal(t, []int{66, 65, 64, 67}, sources)
+}
+
+func TestInterruptController_WriteSource(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+	var signals []int
+	controller.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+
+	// Route source 64 to context 12 with priority 3, masked
+	config := uint64(12) | uint64(3)<<vm.InterruptShiftPriority | 1<<vm.InterruptShiftMasked
+	setControlBlock(memory, flamego.InterruptControlBlockAddress, 7, flamego.DeviceWrite, config, 64, 0)
+	controller.Signal()
+	for cycle := 0; controller.IsBusy(); cycle++ {
+		clockDevice(memory, controller, cycle)
+	}
+	assert.Equal(t, []int{7}, signals)
+	assert.True(t, controller.IsMasked(64))
+	assert.Equal(t, uint8(3), controller.Priority(64))
+
+	controller.Raise(64, 0)
+	controller.Mask(64, false)
+	assert.True(t, controller.IsPending(12))
+}
+
+func TestInterruptController_WriteSource_Rejected(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewInterruptController(memory, flamego.InterruptControlBlockAddress)
+	var signals []int
+	controller.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+
+	// Route source 64 to device 65, which cannot be interrupted
+	config := uint64(65) | uint64(3)<<vm.InterruptShiftPriority
+	setControlBlock(memory, flamego.InterruptControlBlockAddress, 7, flamego.DeviceWrite, config, 64, 0)
+	controller.Signal()
+	for cycle := 0; controller.IsBusy(); cycle++ {
+		clockDevice(memory, controller, cycle)
+	}
+	assert.Equal(t, []int{7}, signals)
+	assert.Equal(t, vm.InterruptRouteNone, controller.Routed(64))
+	assert.Equal(t, uint8(0), controller.Priority(64))
+
+	// Status reports, and clears, the rejection
+	setControlBlock(memory, flamego.InterruptControlBlockAddress, 7, flamego.DeviceStatus, 0, 0, 2048)
+	controller.Signal()
+	for cycle := 0; controller.IsBusy() || controller.MemoryOperation() != flamego.MemoryNone; cycle++ {
+		clockDevice(memory, controller, cycle)
+	}
+	assert.Equal(t, flamego.DeviceErrorUnsupported, readDescriptor(memory, 2048).Error)
+	controller.Signal()
+	for cycle := 0; controller.IsBusy() || controller.MemoryOperation() != flamego.MemoryNone; cycle++ {
+		clockDevice(memory, controller, cycle)
+	}
+	assert.Equal(t, flamego.DeviceErrorNone, readDescriptor(memory, 2048).Error)
+}
@@ -2,27 +2,41 @@ package vm
 
 import (
 	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/isa"
 )
 
+// decoder is implemented by cores which cache the instructions decoded by their contexts.
+type decoder interface {
+	DecodeCache() *isa.DecodeCache
+}
+
 func NewCore(id int, processor flamego.Processor, cache flamego.Cache) *Core {
 	return &Core{
-		id:         id,
-		processor:  processor,
-		cache:      cache,
-		lockHolder: -1,
+		id:        id,
+		processor: processor,
+		cache:     cache,
 	}
 }
 
+// Core is a barrel core; each cycle the issue policy selects a context to fetch an instruction into an 8-stage pipeline.
+//
+// Issued round robin, each context has a single instruction in flight so there are no hazards.
+// The other policies skip contexts with nothing to issue, and a context can issue its next instruction once the previous instruction has loaded data,
+// fetching from the Program Counter which follows it. Younger instructions are discarded if the previous instruction retries, raises an error,
+// goes to sleep, or doesn't continue to the fetched instruction; so errors and effects on the processor are never speculative.
 type Core struct {
-	id        int
-	processor flamego.Processor
-	cache     flamego.Cache
-	contexts  []flamego.Context
-
-	next         int
-	lockHolder   int
-	requiresLock bool
-	acquiredLock bool
+	id         int
+	processor  flamego.Processor
+	cache      flamego.Cache
+	contexts   []flamego.Context
+	threads    []*thread
+	decodes    isa.DecodeCache
+	policy     IssuePolicy
+	statistics CoreStatistics
+
+	next   int                           // Index of the next context in turn
+	pass   uint64                        // Pass of the context last issued by IssuePriority
+	stages [flamego.ContextCount]*flight // Instruction in each stage
 
 	loadRegister0    uint64
 	loadRegister1    uint64
@@ -48,33 +62,32 @@ func (c *Core) Context(index int) flamego.Context {
 
 func (c *Core) AddContext(x flamego.Context) {
 	c.contexts = append(c.contexts, x)
+	t := &thread{
+		context: x.(*Context),
+		weight:  1,
+	}
+	for i := range t.flights {
+		t.flights[i].thread = t
+	}
+	t.loaded = &t.flights[0]
+	t.loaded.isLoaded = true
+	c.threads = append(c.threads, t)
 }
 
-// Index of the next context to fetch an instruction
+// Index of the next context in turn to fetch an instruction
 func (c *Core) NextContext() int {
 	return c.next
 }
 
-func (c *Core) LockHolder() int {
-	return c.lockHolder
-}
-
-func (c *Core) RequiresLock() bool {
-	return c.requiresLock
-}
-
-func (c *Core) AcquiredLock() bool {
-	return c.acquiredLock
-}
-
-func (c *Core) SetAcquiredLock(acquired bool) {
-	c.acquiredLock = acquired
-}
-
 func (c *Core) Cache() flamego.Cache {
 	return c.cache
 }
 
+// DecodeCache returns the instructions decoded by the contexts of the core.
+func (c *Core) DecodeCache() *isa.DecodeCache {
+	return &c.decodes
+}
+
 func (c *Core) LoadRegister0() uint64 {
 	return c.loadRegister0
 }
@@ -107,55 +120,132 @@ func (c *Core) FormatRegister1() uint64 {
 	return c.formatRegister1
 }
 
+// Clock the core, the L2 Cache is clocked by the processor.
 func (c *Core) Clock(cycle int) {
-	// L2 Caches are 10 times slower
-	if cycle%10 == 0 {
-		c.cache.Clock(cycle / 10)
-	}
-
 	// Clock L1 Caches
 	for _, c := range c.contexts {
 		c.InstructionCache().Clock(cycle)
 		c.DataCache().Clock(cycle)
 	}
+	c.statistics.Cycles++
+	if c.policy == IssueRoundRobin {
+		c.clockRoundRobin()
+		return
+	}
+
+	// Advance each instruction to the next stage, the retire stage was emptied in the previous cycle
+	copy(c.stages[1:], c.stages[:len(c.stages)-1])
+	c.stages[0] = nil
+
+	// Run the pipeline in reverse so data flow in intermediate registers are not affected.
+	if x := c.stage(7); x != nil {
+		x.RetireInstruction()
+		c.retired()
+	}
+	if x := c.stage(6); x != nil {
+		x.StoreData(c.formatRegister0, c.formatRegister1)
+		c.advanced(6)
+	}
+	c.formatRegister0, c.formatRegister1 = 0, 0
+	if x := c.stage(5); x != nil {
+		c.formatRegister0, c.formatRegister1 = x.FormatData(c.executeRegister0, c.executeRegister1)
+		c.advanced(5)
+	}
+	c.executeRegister0, c.executeRegister1 = 0, 0
+	if x := c.stage(4); x != nil {
+		c.executeRegister0, c.executeRegister1 = x.ExecuteOperation(c.loadRegister0, c.loadRegister1, c.loadRegister2, c.loadRegister3)
+		c.advanced(4)
+	}
+	c.loadRegister0, c.loadRegister1, c.loadRegister2, c.loadRegister3 = 0, 0, 0, 0
+	if x := c.stage(3); x != nil {
+		c.loadRegister0, c.loadRegister1, c.loadRegister2, c.loadRegister3 = x.LoadData()
+		c.loaded(c.loadRegister0, c.loadRegister1)
+		c.advanced(3)
+	}
+	if x := c.stage(2); x != nil {
+		x.DecodeInstruction()
+		c.advanced(2)
+	}
+	if x := c.stage(1); x != nil {
+		x.LoadInstruction()
+		c.advanced(1)
+	}
+	if t := c.choose(); t != nil {
+		c.issue(t)
+	} else {
+		c.statistics.Empty++
+	}
+
+	// Contexts asleep with nothing in flight count the cycle, as a context asleep in a stage counts it itself
+	for _, t := range c.threads {
+		if t.inflight == 0 && t.context.isAsleep {
+			t.context.sleepCycles++
+		}
+	}
+}
 
+// clockRoundRobin clocks the pipeline with each context in turn in each stage, so the instructions in flight are not tracked.
+func (c *Core) clockRoundRobin() {
 	// Run the pipeline in reverse so data flow in intermediate registers are not affected.
-	c.context(7).RetireInstruction()
+	x := c.context(7)
+	x.RetireInstruction()
+	if x.isValid && !x.isAsleep {
+		c.statistics.Occupied++
+		if x.isRetrying {
+			c.statistics.Retries++
+		}
+	}
 	c.context(6).StoreData(c.formatRegister0, c.formatRegister1)
 	c.formatRegister0, c.formatRegister1 = c.context(5).FormatData(c.executeRegister0, c.executeRegister1)
 	c.executeRegister0, c.executeRegister1 = c.context(4).ExecuteOperation(c.loadRegister0, c.loadRegister1, c.loadRegister2, c.loadRegister3)
 	c.loadRegister0, c.loadRegister1, c.loadRegister2, c.loadRegister3 = c.context(3).LoadData()
 	c.context(2).DecodeInstruction()
-	c.context(1).LoadInstruction()
-	c.context(0).FetchInstruction()
+	x = c.context(1)
+	if x.isValid {
+		x.LoadInstruction()
+		if !x.isValid {
+			c.statistics.Misses++
+		}
+	}
+	x = c.context(0)
+	x.FetchInstruction()
+	if !x.isValid && x.status == "cache busy" {
+		c.statistics.Misses++
+	}
 	c.next = (c.next + 1) % flamego.ContextCount
 
-	// Update Hardware Lock
-	if c.lockHolder == -1 {
-		for i, x := range c.contexts {
-			if x.RequiresLock() {
-				if c.acquiredLock {
-					x.SetAcquiredLock(true)
-					c.lockHolder = i
-				} else {
-					c.requiresLock = true
-				}
-				break
+	for s := 0; s < len(c.stages)-1; s++ {
+		// Context which was in the stage this cycle
+		x := c.context(s + 1)
+		switch {
+		case x.isAsleep:
+			if s == 0 {
+				c.statistics.Idle++
 			}
-		}
-	} else {
-		if !c.contexts[c.lockHolder].RequiresLock() {
-			c.contexts[c.lockHolder].SetAcquiredLock(false)
-			c.requiresLock = false
-			c.lockHolder = -1
+		case x.isValid:
+			if s == 0 {
+				c.statistics.Issued++
+			}
+			c.statistics.Occupied++
 		}
 	}
 }
 
-func (c *Core) context(stage int) flamego.Context {
+// context returns the context in the stage when issued round robin.
+func (c *Core) context(stage int) *Context {
 	index := c.next - stage
 	if index < 0 {
 		index += flamego.ContextCount
 	}
-	return c.contexts[index]
+	return c.threads[index].context
+}
+
+// stage loads the instruction in the stage into its context, returning the context, or nil if the stage is empty.
+func (c *Core) stage(s int) *Context {
+	f := c.stages[s]
+	if f == nil {
+		return nil
+	}
+	c.enter(f)
+	return f.thread.context
 }
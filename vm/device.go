@@ -13,6 +13,12 @@ const (
 	ReadMemoryAddress = flamego.MemoryOperation(5)
 )
 
+// Versions of the devices of this machine, reported by DeviceStatus as 32bit major and 32bit minor versions.
+const (
+	DeviceHardwareVersion = 1 << 32
+	DeviceSoftwareVersion = 1 << 32
+)
+
 func NewDevice(m flamego.Memory, o uint64) *Device {
 	return &Device{
 		memory:       m,
@@ -26,6 +32,8 @@ type Device struct {
 	memoryOffset    uint64
 	memoryOperation flamego.MemoryOperation
 	isBusy          bool
+	isEnabled       bool
+	deviceType      flamego.DeviceType
 	operations      map[flamego.DeviceOperation]func() error
 	operation       flamego.DeviceOperation
 	command         uint64
@@ -36,6 +44,7 @@ type Device struct {
 	OnMemoryRead    func() error
 	OnMemoryWrite   func() error
 	OnSignal        func(int)
+	OnStatus        func(*flamego.DeviceDescriptor) // Completes the descriptor written by Status
 }
 
 func (d *Device) MemoryOffset() uint64 {
@@ -50,6 +59,16 @@ func (d *Device) IsBusy() bool {
 	return d.isBusy
 }
 
+// Type returns the type of the device, reported by Status and the device table.
+func (d *Device) Type() flamego.DeviceType {
+	return d.deviceType
+}
+
+// IsEnabled returns true if the last DeviceEnable or DeviceDisable command enabled the device.
+func (d *Device) IsEnabled() bool {
+	return d.isEnabled
+}
+
 func (d *Device) AddOperation(o flamego.DeviceOperation, f func() error) {
 	d.operations[o] = f
 }
@@ -196,6 +215,12 @@ func (d *Device) CopyCommand() {
 	log.Println("Controller:", d.controller)
 	log.Println("Operation:", d.operation)
 	log.Println("Parameter:", d.parameter)
+	switch d.operation {
+	case flamego.DeviceEnable:
+		d.isEnabled = true
+	case flamego.DeviceDisable:
+		d.isEnabled = false
+	}
 }
 
 func (d *Device) CopyDeviceAddress() {
@@ -223,3 +248,36 @@ func (d *Device) SignalController() {
 	log.Println("Signalling Controller:", d.controller)
 	d.OnSignal(d.controller)
 }
+
+// Status writes the descriptor of the device to MemoryAddress.
+func (d *Device) Status() error {
+	if !d.memory.IsBusy() && d.memory.IsFree() {
+		descriptor := flamego.DeviceDescriptor{
+			Manufacturer:    flamego.DeviceManufacturer,
+			Type:            d.deviceType,
+			HardwareVersion: DeviceHardwareVersion,
+			SoftwareVersion: DeviceSoftwareVersion,
+		}
+		if d.isEnabled {
+			descriptor.State = flamego.DeviceEnabled
+		}
+		if f := d.OnStatus; f != nil {
+			f(&descriptor)
+		}
+		mb := d.memory.Bus()
+		mb.SetLength(flamego.DeviceStatusDescriptorSize)
+		var buffer [flamego.DataSize]byte
+		for i, f := range descriptor.Fields() {
+			binary.BigEndian.PutUint64(buffer[:], f)
+			for j, b := range buffer {
+				mb.Write(i*flamego.DataSize+j, b)
+			}
+		}
+		d.memoryOperation = flamego.MemoryWrite
+		d.memory.WriteBurst(d.memoryAddress, flamego.DeviceStatusDescriptorSize)
+		d.isBusy = false
+		d.operation = flamego.DeviceNone
+		d.SignalController()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func readDescriptor(memory *vm.Memory, address uint64) flamego.DeviceDescriptor {
+	data := memory.Get(address, flamego.DeviceStatusDescriptorSize)
+	var fields [flamego.DeviceStatusFields]uint64
+	for i := range fields {
+		fields[i] = binary.BigEndian.Uint64(data[i*flamego.DataSize:])
+	}
+	return flamego.NewDeviceDescriptor(fields)
+}
+
+func TestDevice_Status(t *testing.T) {
+	for name, tt := range map[string]struct {
+		device     func(*vm.Memory) flamego.Device
+		deviceType flamego.DeviceType
+		capacity   uint64
+		detail     uint64
+	}{
+		"InterruptController": {
+			device: func(m *vm.Memory) flamego.Device {
+				return vm.NewInterruptController(m, flamego.DeviceControlBlockAddress)
+			},
+			deviceType: flamego.DeviceTypeInterruptController,
+		},
+		"Storage": {
+			device: func(m *vm.Memory) flamego.Device {
+				s := vm.NewFileStorage(m, flamego.DeviceControlBlockAddress)
+				s.OpenMemory(make([]byte, 4096))
+				s.SetSectorSize(512)
+				return s
+			},
+			deviceType: flamego.DeviceTypeStorage,
+			capacity:   4096,
+			detail:     512,
+		},
+		"Display": {
+			device: func(m *vm.Memory) flamego.Device {
+				return vm.NewDisplay(m, flamego.DeviceControlBlockAddress, 320, 240)
+			},
+			deviceType: flamego.DeviceTypeDisplay,
+			capacity:   320<<32 | 240,
+			detail:     vm.PixelBytes,
+		},
+		"Timer": {
+			device: func(m *vm.Memory) flamego.Device {
+				return vm.NewTimer(m, flamego.DeviceControlBlockAddress)
+			},
+			deviceType: flamego.DeviceTypeTimer,
+		},
+		"DMA": {
+			device: func(m *vm.Memory) flamego.Device {
+				return vm.NewDMA(m, flamego.DeviceControlBlockAddress)
+			},
+			deviceType: flamego.DeviceTypeDMA,
+			detail:     vm.DMABurstSize,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			memory := vm.NewMemory(MemorySize)
+			device := tt.device(memory)
+			var signals []int
+			device.SetOnSignal(func(c int) {
+				signals = append(signals, c)
+			})
+			for i, operation := range []flamego.DeviceOperation{flamego.DeviceStatus, flamego.DeviceEnable, flamego.DeviceStatus} {
+				setControlBlock(memory, flamego.DeviceControlBlockAddress, 7, operation, 0, 0, 2048)
+				device.Signal()
+				// Descriptor is written after the device signals
+				for cycle := 0; len(signals) <= i || device.(interface {
+					MemoryOperation() flamego.MemoryOperation
+				}).MemoryOperation() != flamego.MemoryNone; cycle++ {
+					if cycle > 100 {
+						t.Fatal("Device never signalled")
+					}
+					clockDevice(memory, device, cycle)
+				}
+				if operation != flamego.DeviceStatus {
+					continue
+				}
+
+				descriptor := readDescriptor(memory, 2048)
+				assert.Equal(t, uint64(flamego.DeviceManufacturer), descriptor.Manufacturer)
+				assert.Equal(t, tt.deviceType, descriptor.Type)
+				assert.Equal(t, uint64(vm.DeviceHardwareVersion), descriptor.HardwareVersion)
+				assert.Equal(t, uint64(vm.DeviceSoftwareVersion), descriptor.SoftwareVersion)
+				assert.Equal(t, tt.capacity, descriptor.Capacity)
+				assert.Equal(t, tt.detail, descriptor.Detail)
+				assert.Equal(t, flamego.DeviceErrorNone, descriptor.Error)
+				if i == 0 {
+					assert.Equal(t, flamego.DeviceDisabled, descriptor.State)
+				} else {
+					assert.Equal(t, flamego.DeviceEnabled, descriptor.State)
+				}
+			}
+			assert.Equal(t, []int{7, 7, 7}, signals)
+		})
+	}
+}
+
+func TestDeviceDescriptor_Fields(t *testing.T) {
+	descriptor := flamego.DeviceDescriptor{
+		Manufacturer:    flamego.DeviceManufacturer,
+		State:           flamego.DeviceEnabled,
+		Type:            flamego.DeviceTypeStorage,
+		HardwareVersion: 1,
+		SoftwareVersion: 2,
+		Capacity:        3,
+		Detail:          4,
+		Error:           flamego.DeviceErrorCapacity,
+	}
+	fields := descriptor.Fields()
+	assert.Equal(t, uint64(flamego.DeviceTypeStorage), fields[flamego.DeviceStatusType])
+	assert.Equal(t, uint64(3), fields[flamego.DeviceStatusCapacity])
+	assert.Equal(t, descriptor, flamego.NewDeviceDescriptor(fields))
+}
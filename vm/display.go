@@ -21,6 +21,8 @@ func NewDisplay(m flamego.Memory, o uint64, w, h int) *Display {
 	d.AddOperation(flamego.DeviceDisable, d.Disable)
 	d.AddOperation(flamego.DeviceWrite, d.FetchFrame)
 	d.OnMemoryRead = d.LoadFrame
+	d.OnStatus = d.describe
+	d.deviceType = flamego.DeviceTypeDisplay
 	return d
 }
 
@@ -34,17 +36,10 @@ func (d *Display) Image() image.Image {
 	return d.buffer
 }
 
-func (d *Display) Status() error {
-	// TODO write to memory
-	// MemoryAddress[0]<-Manufacturer
-	// MemoryAddress[1]<-Current State
-	// MemoryAddress[2]<-Serial Number/Product ID
-	// MemoryAddress[3]<-Hardware Version
-	// MemoryAddress[4]<-Software Version
-	// MemoryAddress[5]<-Size ((32bit Width << 32) | 32bit Height)
-	// MemoryAddress[6]
-	// MemoryAddress[7]
-	return nil
+// describe reports the width and height, and the bytes per pixel.
+func (d *Display) describe(s *flamego.DeviceDescriptor) {
+	s.Capacity = uint64(d.size.Dx())<<32 | uint64(d.size.Dy())
+	s.Detail = PixelBytes
 }
 
 func (d *Display) Enable() error {
@@ -0,0 +1,257 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"encoding/binary"
+	"errors"
+	"log"
+)
+
+// Operations of a DMA engine.
+const (
+	// Copies Parameter bytes from DeviceAddress to MemoryAddress
+	DMACopy = flamego.DeviceWrite
+	// Fills Parameter bytes from MemoryAddress with the 64bit pattern in DeviceAddress
+	DMAFill = flamego.DeviceOperation(6)
+	// Performs the Parameter transfers described by the table at MemoryAddress
+	DMAScatterGather = flamego.DeviceOperation(7)
+)
+
+const (
+	// Unit: Bytes
+	DMABurstSize = 64
+	// Operation, Length, Source or Pattern, and Destination of a transfer in a scatter-gather table
+	DMADescriptorSize = 4 * flamego.DataSize
+)
+
+// Errors of a transfer, which is abandoned along with the rest of the operation, and the controller signalled.
+var (
+	ErrDMATransfer = errors.New("Unrecognized DMA transfer")
+	ErrDMARange    = errors.New("DMA transfer exceeds memory")
+)
+
+// sized is implemented by memories which report their size in bytes.
+type sized interface {
+	Size() uint64
+}
+
+var _ (flamego.Device) = (*DMA)(nil)
+
+func NewDMA(m flamego.Memory, o uint64) *DMA {
+	d := &DMA{
+		Device: *NewDevice(m, o),
+	}
+	d.AddOperation(flamego.DeviceStatus, d.Status)
+	d.AddOperation(flamego.DeviceEnable, d.Enable)
+	d.AddOperation(flamego.DeviceDisable, d.Disable)
+	d.AddOperation(DMACopy, d.Copy)
+	d.AddOperation(DMAFill, d.Fill)
+	d.AddOperation(DMAScatterGather, d.ScatterGather)
+	d.OnMemoryRead = d.onRead
+	d.OnStatus = d.describe
+	d.deviceType = flamego.DeviceTypeDMA
+	if m, ok := m.(sized); ok {
+		d.size = m.Size()
+	}
+	return d
+}
+
+// DMA transfers between regions of memory in bursts, signalling the controller once every transfer has been written.
+// Transfers bypass the caches, so the source must be flushed, and the destination cleared, by the program.
+type DMA struct {
+	Device
+	isActive    bool
+	isLoading   bool                    // Whether a descriptor is being read
+	transfer    flamego.DeviceOperation // DMACopy or DMAFill
+	length      uint64                  // Bytes of the current transfer
+	remaining   uint64                  // Bytes of the current transfer yet to be written
+	source      uint64                  // Address read by a copy, or pattern written by a fill
+	destination uint64
+	descriptors uint64 // Transfers of the scatter-gather table yet to be started
+	table       uint64 // Address of the next descriptor
+	data        []byte // Bytes read by a copy, yet to be written
+	transferred uint64
+	size        uint64 // Bytes of memory, zero if unknown so transfers are not checked
+	err         error  // Error of the last operation, cleared by Status
+}
+
+// Transferred returns the number of bytes written by the engine.
+func (d *DMA) Transferred() uint64 {
+	return d.transferred
+}
+
+// Error returns the error of the last operation, or nil if none failed since the last Status.
+func (d *DMA) Error() error {
+	return d.err
+}
+
+// describe reports the bytes transferred, the burst size, and the error of the last operation, which is cleared.
+func (d *DMA) describe(s *flamego.DeviceDescriptor) {
+	s.Capacity = d.transferred
+	s.Detail = DMABurstSize
+	switch d.err {
+	case nil:
+	case ErrDMARange:
+		s.Error = flamego.DeviceErrorCapacity
+	default:
+		s.Error = flamego.DeviceErrorUnsupported
+	}
+	d.err = nil
+}
+
+func (d *DMA) Enable() error {
+	d.isBusy = false
+	d.operation = flamego.DeviceNone
+	d.SignalController()
+	return nil
+}
+
+func (d *DMA) Disable() error {
+	d.isBusy = false
+	d.operation = flamego.DeviceNone
+	return nil
+}
+
+func (d *DMA) Copy() error {
+	if !d.isActive && !d.start(DMACopy, d.parameter, d.deviceAddress, d.memoryAddress) {
+		return nil
+	}
+	return d.step()
+}
+
+func (d *DMA) Fill() error {
+	if !d.isActive && !d.start(DMAFill, d.parameter, d.deviceAddress, d.memoryAddress) {
+		return nil
+	}
+	return d.step()
+}
+
+func (d *DMA) ScatterGather() error {
+	if !d.isActive {
+		d.isActive = true
+		d.descriptors = d.parameter
+		d.table = d.memoryAddress
+		log.Println("DMA Scatter-Gather:", d.descriptors, "Table:", d.table)
+	}
+	return d.step()
+}
+
+// start begins the transfer, returning false if it was abandoned as it exceeds memory.
+func (d *DMA) start(transfer flamego.DeviceOperation, length, source, destination uint64) bool {
+	if !d.contains(destination, length) || (transfer == DMACopy && !d.contains(source, length)) {
+		d.fail(ErrDMARange)
+		return false
+	}
+	d.isActive = true
+	d.transfer = transfer
+	d.length = length
+	d.remaining = length
+	d.source = source
+	d.destination = destination
+	d.data = nil
+	log.Println("DMA Transfer:", transfer, "Length:", length, "Source:", source, "Destination:", destination)
+	return true
+}
+
+// contains returns true if the given number of bytes from the address are in memory.
+func (d *DMA) contains(address, length uint64) bool {
+	end := address + length
+	return d.size == 0 || (end >= address && end <= d.size)
+}
+
+// fail abandons the operation, recording the error and signalling the controller.
+func (d *DMA) fail(err error) {
+	log.Println("DMA Error:", err)
+	d.err = err
+	d.isLoading = false
+	d.descriptors = 0
+	d.remaining = 0
+	d.data = nil
+	d.complete()
+}
+
+// complete signals the controller that the operation has finished.
+func (d *DMA) complete() {
+	d.isActive = false
+	d.isBusy = false
+	d.operation = flamego.DeviceNone
+	d.SignalController()
+}
+
+// step issues the next request of the operation, or completes the operation once every transfer has been written.
+func (d *DMA) step() error {
+	if d.memory.IsBusy() || !d.memory.IsFree() {
+		return nil
+	}
+	switch {
+	case d.remaining > 0 && d.transfer == DMACopy && d.data == nil:
+		d.memoryOperation = flamego.MemoryRead
+		d.memory.ReadBurst(d.source, d.burst())
+	case d.remaining > 0:
+		length := d.burst()
+		mb := d.memory.Bus()
+		mb.SetLength(length)
+		for i := 0; i < length; i++ {
+			if d.transfer == DMACopy {
+				mb.Write(i, d.data[i])
+			} else {
+				offset := (d.length - d.remaining + uint64(i)) % flamego.DataSize
+				mb.Write(i, byte(d.source>>(8*(flamego.DataSize-1-offset))))
+			}
+		}
+		d.memoryOperation = flamego.MemoryWrite
+		d.memory.WriteBurst(d.destination, length)
+		if d.transfer == DMACopy {
+			d.source += uint64(length)
+			d.data = nil
+		}
+		d.destination += uint64(length)
+		d.remaining -= uint64(length)
+		d.transferred += uint64(length)
+	case d.descriptors > 0 && !d.contains(d.table, DMADescriptorSize):
+		d.fail(ErrDMARange)
+	case d.descriptors > 0:
+		d.isLoading = true
+		d.memoryOperation = flamego.MemoryRead
+		d.memory.ReadBurst(d.table, DMADescriptorSize)
+	default:
+		log.Println("DMA Complete")
+		d.complete()
+	}
+	return nil
+}
+
+// burst returns the number of bytes of the current transfer moved by the next request.
+func (d *DMA) burst() int {
+	if d.remaining < DMABurstSize {
+		return int(d.remaining)
+	}
+	return DMABurstSize
+}
+
+func (d *DMA) onRead() error {
+	mb := d.memory.Bus()
+	if d.isLoading {
+		d.isLoading = false
+		var buffer [DMADescriptorSize]byte
+		for i := range buffer {
+			buffer[i] = mb.Read(i)
+		}
+		transfer := flamego.DeviceOperation(binary.BigEndian.Uint64(buffer[0:]))
+		if transfer != DMACopy && transfer != DMAFill {
+			log.Println("Unrecognized DMA Transfer:", transfer)
+			d.fail(ErrDMATransfer)
+			return nil
+		}
+		if d.start(transfer, binary.BigEndian.Uint64(buffer[8:]), binary.BigEndian.Uint64(buffer[16:]), binary.BigEndian.Uint64(buffer[24:])) {
+			d.descriptors--
+			d.table += DMADescriptorSize
+		}
+		return nil
+	}
+	d.data = make([]byte, mb.Length())
+	for i := range d.data {
+		d.data[i] = mb.Read(i)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDMA_Copy(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	dma := vm.NewDMA(memory, flamego.DeviceControlBlockAddress)
+	var signals []int
+	dma.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+	source := make([]byte, 100)
+	for i := range source {
+		source[i] = byte(i + 1)
+	}
+	memory.Set(1024, source)
+
+	// Copy 100 bytes from 1024 to 2048, signalling context 2
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 2, vm.DMACopy, 100, 1024, 2048)
+	dma.Signal()
+	clockUntilIdle(t, memory, dma)
+
+	assert.Equal(t, []int{2}, signals)
+	assert.Equal(t, source, memory.Get(2048, 100))
+	assert.Equal(t, make([]byte, 8), memory.Get(2148, 8))
+	assert.Equal(t, uint64(100), dma.Transferred())
+}
+
+func TestDMA_Fill(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	dma := vm.NewDMA(memory, flamego.DeviceControlBlockAddress)
+	var signals []int
+	dma.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+
+	// Fill 132 bytes from 2048 with double pixels
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 4, vm.DMAFill, 132, 0xff0000ff00ff00ff, 2048)
+	dma.Signal()
+	clockUntilIdle(t, memory, dma)
+
+	assert.Equal(t, []int{4}, signals)
+	data := memory.Get(2048, 136)
+	for i := 0; i < 128; i += 8 {
+		assert.Equal(t, []byte{0xff, 0, 0, 0xff, 0, 0xff, 0, 0xff}, data[i:i+8])
+	}
+	assert.Equal(t, []byte{0xff, 0, 0, 0xff, 0, 0, 0, 0}, data[128:])
+}
+
+func TestDMA_ScatterGather(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	controller := vm.NewMemoryController(memory)
+	dma := vm.NewDMA(controller.AddPort(vm.PortDMA), flamego.DeviceControlBlockAddress)
+	var signals []int
+	dma.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+	memory.Set(1024, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+	// Gather two halves in reverse order, then fill after them
+	table := make([]byte, 3*vm.DMADescriptorSize)
+	for i, d := range [][4]uint64{
+		{uint64(vm.DMACopy), 8, 1032, 4096},
+		{uint64(vm.DMACopy), 8, 1024, 4104},
+		{uint64(vm.DMAFill), 8, 0x0101010101010101, 4112},
+	} {
+		for j, v := range d {
+			binary.BigEndian.PutUint64(table[i*vm.DMADescriptorSize+j*flamego.DataSize:], v)
+		}
+	}
+	memory.Set(3072, table)
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 1, vm.DMAScatterGather, 3, 0, 3072)
+	dma.Signal()
+	for cycle := 0; dma.IsBusy(); cycle++ {
+		if cycle > 1000 {
+			t.Fatal("DMA never completed")
+		}
+		controller.Clock(cycle)
+		dma.Clock(cycle)
+	}
+
+	assert.Equal(t, []int{1}, signals)
+	assert.Equal(t, []byte{9, 10, 11, 12, 13, 14, 15, 16, 1, 2, 3, 4, 5, 6, 7, 8, 1, 1, 1, 1, 1, 1, 1, 1}, memory.Get(4096, 24))
+	assert.Equal(t, uint64(24), dma.Transferred())
+
+	// Unrecognized transfers abandon the operation, signalling the controller
+	binary.BigEndian.PutUint64(table, uint64(flamego.DeviceStatus))
+	memory.Set(3072, table)
+	dma.Signal()
+	for cycle := 0; dma.IsBusy(); cycle++ {
+		if cycle > 1000 {
+			t.Fatal("DMA never completed")
+		}
+		controller.Clock(cycle)
+		dma.Clock(cycle)
+	}
+	assert.Equal(t, []int{1, 1}, signals)
+	assert.Equal(t, vm.ErrDMATransfer, dma.Error())
+	assert.Equal(t, uint64(24), dma.Transferred())
+}
+
+func TestDMA_Error(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	dma := vm.NewDMA(memory, flamego.DeviceControlBlockAddress)
+	var signals []int
+	dma.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+	table := make([]byte, vm.DMADescriptorSize)
+	binary.BigEndian.PutUint64(table, uint64(vm.DMACopy))
+	binary.BigEndian.PutUint64(table[8:], 8)
+	binary.BigEndian.PutUint64(table[16:], MemorySize)
+	binary.BigEndian.PutUint64(table[24:], 2048)
+	memory.Set(3072, table)
+
+	for name, tt := range map[string]struct {
+		operation                   flamego.DeviceOperation
+		length, source, destination uint64
+		err                         error
+		code                        flamego.DeviceError
+	}{
+		"Copy": {
+			operation:   vm.DMACopy,
+			length:      8,
+			source:      1024,
+			destination: 2048,
+		},
+		"CopySource": {
+			operation:   vm.DMACopy,
+			length:      16,
+			source:      MemorySize - 8,
+			destination: 2048,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+		"CopyDestination": {
+			operation:   vm.DMACopy,
+			length:      8,
+			source:      1024,
+			destination: MemorySize,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+		"CopyOverflow": {
+			operation:   vm.DMACopy,
+			length:      8,
+			source:      ^uint64(0) - 3,
+			destination: 2048,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+		"FillDestination": {
+			operation:   vm.DMAFill,
+			length:      8,
+			source:      0xff,
+			destination: MemorySize - 4,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+		"ScatterGatherTable": {
+			operation:   vm.DMAScatterGather,
+			length:      1,
+			destination: MemorySize - 8,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+		"ScatterGatherDescriptor": {
+			operation:   vm.DMAScatterGather,
+			length:      1,
+			destination: 3072,
+			err:         vm.ErrDMARange,
+			code:        flamego.DeviceErrorCapacity,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			signals = nil
+			memory.Set(2048, make([]byte, 8))
+			memory.Set(1024, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+			setControlBlock(memory, flamego.DeviceControlBlockAddress, 3, tt.operation, tt.length, tt.source, tt.destination)
+			dma.Signal()
+			clockUntilIdle(t, memory, dma)
+			assert.Equal(t, []int{3}, signals)
+			assert.Equal(t, tt.err, dma.Error())
+			if tt.err == nil {
+				assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, memory.Get(2048, 8))
+			} else {
+				assert.Equal(t, make([]byte, 8), memory.Get(2048, 8))
+			}
+
+			// Status reports, and clears, the error
+			setControlBlock(memory, flamego.DeviceControlBlockAddress, 3, flamego.DeviceStatus, 0, 0, 4096)
+			dma.Signal()
+			clockUntilIdle(t, memory, dma)
+			assert.Equal(t, tt.code, readDescriptor(memory, 4096).Error)
+			assert.Nil(t, dma.Error())
+		})
+	}
+}
+
+func TestDMA_Machine(t *testing.T) {
+	machine := vm.NewMachine()
+	dma := vm.NewDMA(machine.Controller.AddPort(vm.PortDMA), flamego.DeviceControlBlockAddress)
+	machine.Processor.AddDevice(dma)
+	memory := machine.Memory
+	memory.Set(1024, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 0, vm.DMACopy, 8, 1024, 2048)
+	dma.Signal()
+	for i := 0; dma.IsBusy(); i++ {
+		if i > 1000000 {
+			t.Fatal("DMA never completed")
+		}
+		machine.Clock()
+	}
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, memory.Get(2048, 8))
+}
+
+func clockUntilIdle(t *testing.T, memory *vm.Memory, dma *vm.DMA) {
+	t.Helper()
+	// Idle once the last request, such as the descriptor written by Status, has been served
+	for cycle := 0; dma.IsBusy() || dma.MemoryOperation() != flamego.MemoryNone; cycle++ {
+		if cycle > 1000 {
+			t.Fatal("DMA never completed")
+		}
+		clockDevice(memory, dma, cycle)
+	}
+}
@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"io"
+)
+
+// NewMemoryImage returns an image held in the buffer, which isn't grown by writes.
+func NewMemoryImage(buffer []byte) *MemoryImage {
+	return &MemoryImage{
+		buffer: buffer,
+	}
+}
+
+// MemoryImage is a storage image held in host memory, such as for tests.
+type MemoryImage struct {
+	buffer []byte
+}
+
+func (m *MemoryImage) Bytes() []byte {
+	return m.buffer
+}
+
+func (m *MemoryImage) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 || offset >= int64(len(m.buffer)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.buffer[offset:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *MemoryImage) WriteAt(p []byte, offset int64) (int, error) {
+	if offset < 0 || offset >= int64(len(m.buffer)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(m.buffer[offset:], p)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+func (m *MemoryImage) Close() error {
+	return nil
+}
@@ -0,0 +1,401 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/isa"
+	"fmt"
+)
+
+const (
+	// Unit: Cycles
+	// Instructions of a context are issued at least this far apart, so each instruction loads data after the previous instruction stores it,
+	// and executes after the previous instruction retires.
+	IssueInterval = 3
+)
+
+// stride is the pass advanced by an issue of a context with weight 1 under IssuePriority.
+const stride = 1 << 16
+
+// IssuePolicy selects the context issuing an instruction into the fetch stage of a barrel core each cycle.
+type IssuePolicy int
+
+const (
+	// Contexts issue in turn, whether or not they have an instruction to issue, so each context has at most one instruction in flight
+	IssueRoundRobin IssuePolicy = iota
+	// The next context in turn with an instruction to issue, skipping contexts which are asleep or waiting for an older instruction
+	IssueSkipIdle
+	// The context with an instruction to issue which has the smallest share of the pipeline relative to its weight
+	IssuePriority
+	// The same context issues until it misses in a cache, or goes to sleep, then the next context in turn takes over
+	IssueSwitchOnMiss
+)
+
+func (p IssuePolicy) String() string {
+	switch p {
+	case IssueRoundRobin:
+		return "roundrobin"
+	case IssueSkipIdle:
+		return "skipidle"
+	case IssuePriority:
+		return "priority"
+	case IssueSwitchOnMiss:
+		return "switchonmiss"
+	}
+	return "unknown"
+}
+
+func ParseIssuePolicy(s string) (IssuePolicy, error) {
+	switch s {
+	case "roundrobin":
+		return IssueRoundRobin, nil
+	case "skipidle":
+		return IssueSkipIdle, nil
+	case "priority":
+		return IssuePriority, nil
+	case "switchonmiss":
+		return IssueSwitchOnMiss, nil
+	}
+	return 0, fmt.Errorf("Unrecognized Issue Policy: %s", s)
+}
+
+// CoreStatistics counts the use of the pipeline of a barrel core.
+type CoreStatistics struct {
+	Cycles   uint64 // Cycles clocked, or skipped while quiescent
+	Issued   uint64 // Instructions entering the pipeline, including retries
+	Idle     uint64 // Cycles the fetch stage was given to a context which was asleep
+	Empty    uint64 // Cycles no context was able to issue an instruction
+	Occupied uint64 // Stages holding an instruction of an awake context, summed over cycles
+	Misses   uint64 // Instructions discarded while the instruction cache was busy or missed
+	Retries  uint64 // Instructions issued again while waiting for a cache, lock, or mailbox
+	Flushes  uint64 // Instructions discarded after an older instruction of the same context retried, raised an error, went to sleep, or changed control flow
+}
+
+// Occupancy returns the fraction of stages holding an instruction of an awake context.
+func (s CoreStatistics) Occupancy() float64 {
+	if s.Cycles == 0 {
+		return 0
+	}
+	return float64(s.Occupied) / float64(s.Cycles*flamego.ContextCount)
+}
+
+// thread holds the instructions a context has in flight through a barrel core.
+type thread struct {
+	context  *Context
+	flights  [flamego.ContextCount/IssueInterval + 1]flight
+	loaded   *flight // Instruction whose state is held by the context
+	retry    *flight // Instruction to issue again
+	inflight int     // Instructions in the pipeline
+	weight   int
+	pass     uint64 // Issues scaled inversely by weight, for IssuePriority
+	missed   bool   // Whether a cache missed since the context last took over, for IssueSwitchOnMiss
+}
+
+// flight is an instruction in flight through a barrel core, its state is held here while another instruction of the same context is loaded.
+type flight struct {
+	thread      *thread
+	pc          uint64
+	opcode      uint32
+	instruction flamego.Instruction
+	state       flamego.InstructionState
+	isValid     bool
+	isAligned   bool
+	isRetrying  bool
+	isLoaded    bool   // Whether the state of the instruction is held by the context
+	active      bool   // Whether the instruction is in the pipeline
+	serializing bool   // Younger instructions aren't issued as the next Program Counter, or the effect of the instruction, is unknown until it retires
+	next        uint64 // Program Counter following the instruction, once it has loaded data
+}
+
+// free returns an instruction which is not in flight.
+func (t *thread) free() *flight {
+	for i := range t.flights {
+		if f := &t.flights[i]; !f.active && f != t.retry {
+			return f
+		}
+	}
+	panic("No free flight")
+}
+
+// SetIssuePolicy sets the policy selecting the context to issue each cycle.
+func (c *Core) SetIssuePolicy(policy IssuePolicy) {
+	c.policy = policy
+}
+
+func (c *Core) IssuePolicy() IssuePolicy {
+	return c.policy
+}
+
+// SetWeight sets the share of the pipeline given to the context under IssuePriority, relative to the other contexts.
+func (c *Core) SetWeight(index, weight int) {
+	if weight < 1 {
+		panic("Invalid weight")
+	}
+	c.threads[index].weight = weight
+}
+
+func (c *Core) Weight(index int) int {
+	return c.threads[index].weight
+}
+
+func (c *Core) Statistics() CoreStatistics {
+	return c.statistics
+}
+
+// choose returns the thread to issue an instruction this cycle, or nil if the fetch stage stays empty.
+func (c *Core) choose() *thread {
+	count := len(c.threads)
+	switch c.policy {
+	case IssueSkipIdle:
+		for i := 0; i < count; i++ {
+			index := (c.next + i) % count
+			if t := c.threads[index]; c.isReady(t) {
+				c.next = (index + 1) % count
+				return t
+			}
+		}
+	case IssuePriority:
+		var best *thread
+		for i := 0; i < count; i++ {
+			index := (c.next + i) % count
+			if t := c.threads[index]; c.isReady(t) && (best == nil || t.pass < best.pass) {
+				best = t
+				c.next = (index + 1) % count
+			}
+		}
+		if best != nil {
+			// A context which was idle doesn't catch up by taking over the pipeline
+			if best.inflight == 0 && best.pass < c.pass {
+				best.pass = c.pass
+			}
+			c.pass = best.pass
+			best.pass += stride / uint64(best.weight)
+		}
+		return best
+	case IssueSwitchOnMiss:
+		current := c.threads[c.next]
+		if !current.missed {
+			if c.isReady(current) {
+				return current
+			}
+			if x := current.context; !x.isAsleep || current.inflight > 0 {
+				// Waiting for an older instruction
+				return nil
+			}
+		}
+		current.missed = false
+		for i := 1; i <= count; i++ {
+			index := (c.next + i) % count
+			if t := c.threads[index]; c.isReady(t) {
+				c.next = index
+				t.missed = false
+				return t
+			}
+		}
+	default:
+		t := c.threads[c.next]
+		c.next = (c.next + 1) % flamego.ContextCount
+		return t
+	}
+	return nil
+}
+
+// isReady returns true if the context can issue an instruction this cycle.
+func (c *Core) isReady(t *thread) bool {
+	if t.retry != nil {
+		return true
+	}
+	x := t.context
+	f, stage := c.youngest(t)
+	if f == nil {
+		return !x.isAsleep || x.nextInterrupt >= 0 || x.isSignalPending()
+	}
+	if stage < IssueInterval || f.serializing {
+		return false
+	}
+	if x.isAsleep || x.nextInterrupt >= 0 || x.isSignalPending() {
+		// Interrupts are only taken once the older instructions have retired
+		return false
+	}
+	if !x.isInterrupted && f.next+x.registers[flamego.RProgramStart] >= x.registers[flamego.RProgramLimit] {
+		// Program access errors are only raised once the older instructions have retired
+		return false
+	}
+	return true
+}
+
+// youngest returns the youngest instruction of the thread in the pipeline, and its stage.
+func (c *Core) youngest(t *thread) (*flight, int) {
+	if t.inflight == 0 {
+		return nil, 0
+	}
+	for s, f := range c.stages {
+		if f != nil && f.thread == t {
+			return f, s
+		}
+	}
+	return nil, 0
+}
+
+// issue starts an instruction of the thread in the fetch stage.
+func (c *Core) issue(t *thread) {
+	x := t.context
+	f := t.retry
+	if f == nil {
+		pc := x.registers[flamego.RProgramCounter]
+		if y, _ := c.youngest(t); y != nil {
+			// Fetch the instruction following the youngest instruction in flight
+			pc = y.next
+		}
+		f = t.free()
+		c.enter(f)
+		x.registers[flamego.RProgramCounter] = pc
+	} else {
+		c.enter(f)
+	}
+	t.retry = nil
+	f.active = true
+	t.inflight++
+	c.stages[0] = f
+
+	x.FetchInstruction()
+	if x.isAsleep {
+		c.statistics.Idle++
+	} else if x.isValid {
+		c.statistics.Issued++
+	}
+	c.advanced(0)
+}
+
+// enter loads the state of the instruction into its context, saving the state of the instruction previously loaded.
+func (c *Core) enter(f *flight) {
+	if !f.isLoaded {
+		c.swap(f)
+	}
+}
+
+func (c *Core) swap(f *flight) {
+	t := f.thread
+	l := t.loaded
+	x := t.context
+	l.pc = x.registers[flamego.RProgramCounter]
+	l.opcode = x.opcode
+	l.instruction = x.instruction
+	l.state = x.state
+	l.isValid = x.isValid
+	l.isAligned = x.isAligned
+	l.isRetrying = x.isRetrying
+
+	x.registers[flamego.RProgramCounter] = f.pc
+	x.opcode = f.opcode
+	x.instruction = f.instruction
+	x.state = f.state
+	x.isValid = f.isValid
+	x.isAligned = f.isAligned
+	x.isRetrying = f.isRetrying
+	l.isLoaded = false
+	f.isLoaded = true
+	t.loaded = f
+}
+
+// remove takes the instruction in the stage out of the pipeline.
+func (c *Core) remove(stage int) {
+	f := c.stages[stage]
+	c.stages[stage] = nil
+	f.active = false
+	f.thread.inflight--
+}
+
+// advanced updates the pipeline after the instruction in the stage has advanced, removing it if it was discarded.
+func (c *Core) advanced(stage int) {
+	f := c.stages[stage]
+	t := f.thread
+	x := t.context
+	if !x.isValid {
+		if x.status == "cache busy" || x.status == "cache miss" {
+			c.statistics.Misses++
+			t.missed = true
+		}
+		c.remove(stage)
+		return
+	}
+	if !x.isAsleep {
+		c.statistics.Occupied++
+	}
+	if t.inflight > 1 && (x.isAsleep || x.nextInterrupt >= 0) {
+		// The younger instructions would not have been issued
+		c.flush(t, stage)
+	}
+}
+
+// loaded records the Program Counter following the instruction in the load data stage, and whether it is serializing.
+func (c *Core) loaded(a, b uint64) {
+	f := c.stages[3]
+	x := f.thread.context
+	f.next = x.registers[flamego.RProgramCounter] + flamego.InstructionSize
+	f.serializing = false
+	switch i := x.instruction.(type) {
+	case *isa.Jump:
+		// Jumps are resolved by the condition loaded
+		f.next, _ = i.Execute(x, a, b, 0, 0)
+	case nil, *isa.Call, *isa.Return, *isa.Interrupt, *isa.Uninterrupt, *isa.Halt, *isa.Sleep, *isa.Exit, *isa.Clear, *isa.Spawn, *isa.Receive:
+		// Control flow or mode changes, registers are written on retiring, or the instruction cache is required
+		f.serializing = true
+	}
+}
+
+// retired takes the instruction in the retire stage out of the pipeline, keeping it to issue again if it is retrying,
+// and discarding younger instructions of the context which would not have been issued.
+func (c *Core) retired() {
+	f := c.stages[7]
+	t := f.thread
+	x := t.context
+	c.remove(7)
+	if !x.isValid {
+		return
+	}
+	if x.isAsleep {
+		c.flush(t, 7)
+		return
+	}
+	c.statistics.Occupied++
+	if x.isRetrying {
+		c.statistics.Retries++
+		t.retry = f
+		t.missed = true
+		c.flush(t, 7)
+	} else if x.nextInterrupt >= 0 {
+		c.flush(t, 7)
+	} else if y := c.following(t); y != nil && y.pc != x.registers[flamego.RProgramCounter] {
+		c.flush(t, 7)
+	}
+}
+
+// following returns the oldest instruction of the thread in the pipeline before the retire stage.
+func (c *Core) following(t *thread) *flight {
+	if t.inflight == 0 {
+		return nil
+	}
+	for s := len(c.stages) - 2; s >= 0; s-- {
+		if f := c.stages[s]; f != nil && f.thread == t {
+			return f
+		}
+	}
+	return nil
+}
+
+// flush discards the instructions of the thread in the stages before the given stage.
+func (c *Core) flush(t *thread, stage int) {
+	if t.inflight == 0 {
+		return
+	}
+	for s := 0; s < stage; s++ {
+		if f := c.stages[s]; f != nil && f.thread == t {
+			if s == 1 && f.isValid {
+				// Discard the instruction being read
+				t.context.iCache.Free()
+			}
+			c.remove(s)
+			c.statistics.Flushes++
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var issuePolicies = []vm.IssuePolicy{
+	vm.IssueSkipIdle,
+	vm.IssuePriority,
+	vm.IssueSwitchOnMiss,
+}
+
+const countdownProgram = `
+loadc 1000 r16
+#Loop
+add r17 r16 r17
+subtract r16 r1 r16
+jnz r16 #Loop
+halt
+`
+
+const spinProgram = `
+#Loop
+add r16 r1 r16
+jump #Loop
+`
+
+func TestCore_IssuePolicy_Samples(t *testing.T) {
+	programs := loadSamples(t, samples...)
+	programs["error"] = errorProgram
+	programs["spawn"] = parallelProgram
+	for _, p := range issuePolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			// Policies only change when contexts issue, not the results of their programs
+			_, machines := assertSameAsBarrel(t, vm.Config{Issue: p}, programs)
+			// Sum of the squares of the core identifiers of each child
+			assert.Equal(t, uint64(140), machines["spawn"].Processor.Core(0).Context(0).ReadRegister(flamego.R23))
+		})
+	}
+}
+
+func TestCore_SkipIdle(t *testing.T) {
+	barrel := runMachine(t, vm.Config{}, strings.NewReader(countdownProgram))
+	m := runMachine(t, vm.Config{Issue: vm.IssueSkipIdle}, strings.NewReader(countdownProgram))
+	assert.Equal(t, uint64(500500), m.Processor.Core(0).Context(0).ReadRegister(flamego.R17))
+
+	// A single context issues every IssueInterval cycles, instead of every 8 cycles
+	assert.Less(t, m.Tick, barrel.Tick/2)
+	s1 := barrel.Processor.Core(0).(*vm.Core).Statistics()
+	s2 := m.Processor.Core(0).(*vm.Core).Statistics()
+	assert.Greater(t, s2.Occupancy(), 2*s1.Occupancy())
+	assert.Zero(t, s2.Idle)
+	// Jumps are resolved before the next instruction is fetched
+	assert.Zero(t, s2.Flushes)
+}
+
+func TestCore_Priority(t *testing.T) {
+	m := vm.NewMachineWithConfig(vm.Config{
+		Issue:   vm.IssuePriority,
+		Weights: []int{2},
+	})
+	m.Memory.Set(0, assemble(t, strings.NewReader(spinProgram)))
+	for i := 0; i < flamego.ContextCount; i++ {
+		m.Processor.Signal(flamego.InterruptSourceHost, i)
+	}
+	for m.Tick < 50000 {
+		m.Clock()
+	}
+	c := m.Processor.Core(0)
+	first := c.Context(0).RetiredInstructions()
+	for i := 1; i < flamego.ContextCount; i++ {
+		// Context 0 issues twice as often as the others
+		other := c.Context(i).RetiredInstructions()
+		assert.Greater(t, other, uint64(0))
+		assert.InDelta(t, 2, float64(first)/float64(other), 0.2)
+	}
+}
+
+func TestCore_SwitchOnMiss(t *testing.T) {
+	run := func(p vm.IssuePolicy) *vm.Machine {
+		m := vm.NewMachineWithConfig(vm.Config{Issue: p})
+		m.Memory.Set(0, assemble(t, strings.NewReader(spinProgram)))
+		m.Processor.Signal(flamego.InterruptSourceHost, 0)
+		m.Processor.Signal(flamego.InterruptSourceHost, 1)
+		for m.Tick < 50000 {
+			m.Clock()
+		}
+		return m
+	}
+	skip := run(vm.IssueSkipIdle).Processor.Core(0)
+	som := run(vm.IssueSwitchOnMiss).Processor.Core(0)
+	// Contexts interleave when skipping idle contexts
+	assert.InDelta(t, skip.Context(0).RetiredInstructions(), skip.Context(1).RetiredInstructions(), 10)
+	// Once the instructions are cached, the context which took over keeps the pipeline
+	first, second := som.Context(0).RetiredInstructions(), som.Context(1).RetiredInstructions()
+	if first < second {
+		first, second = second, first
+	}
+	assert.Greater(t, first, uint64(10000))
+	assert.Less(t, second, first/10)
+	assert.Greater(t, som.(*vm.Core).Statistics().Empty, skip.(*vm.Core).Statistics().Empty)
+}
+
+func TestCore_IssuePolicy_FastForward(t *testing.T) {
+	run := func(fastForward bool) (*vm.Processor, int) {
+		memory := vm.NewMemory(MemorySize)
+		memory.Set(0, assemble(t, strings.NewReader(timerProgram)))
+		processor := newProcessor(memory)
+		processor.Core(0).(*vm.Core).SetIssuePolicy(vm.IssueSkipIdle)
+		timer := vm.NewTimer(memory, flamego.DeviceControlBlockAddress)
+		processor.AddDevice(timer)
+		// Periodic timer signalling context 0
+		setControlBlock(memory, flamego.DeviceControlBlockAddress, 0, flamego.DeviceWrite, 4, vm.TimerPeriodic, 0)
+		processor.Signal(flamego.InterruptSourceHost, 0)
+
+		cycle := 0
+		for ; !processor.HasHalted(); cycle++ {
+			if cycle > 1000000 {
+				t.Fatal("Processor never halted")
+			}
+			if fastForward {
+				cycle += processor.FastForward(cycle)
+			}
+			processor.Clock(cycle)
+		}
+		return processor, cycle
+	}
+	p1, c1 := run(false)
+	p2, c2 := run(true)
+	assert.Equal(t, c1, c2)
+	assert.Equal(t, p1.Core(0).(*vm.Core).Statistics(), p2.Core(0).(*vm.Core).Statistics())
+	for i := 0; i < flamego.ContextCount; i++ {
+		x1 := p1.Core(0).Context(i).(*vm.Context)
+		x2 := p2.Core(0).Context(i).(*vm.Context)
+		for r := flamego.R0; r <= flamego.R31; r++ {
+			assert.Equal(t, x1.ReadRegister(r), x2.ReadRegister(r))
+		}
+		assert.Equal(t, x1.SleepCycles(), x2.SleepCycles())
+	}
+	assert.Equal(t, uint64(3), p1.Core(0).Context(0).ReadRegister(flamego.R17))
+}
@@ -0,0 +1,212 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+	"log"
+	"math/bits"
+	"strings"
+)
+
+const (
+	// Unit: Cycles
+	LockThreshold = 1000000
+)
+
+func NewLock(id int) *Lock {
+	return &Lock{
+		id:       id,
+		holder:   -1,
+		previous: -1,
+	}
+}
+
+// Lock is a hardware lock arbitrated by the processor between contexts.
+type Lock struct {
+	id       int
+	holder   int
+	previous int
+	since    int
+	waiting  []int
+	warned   bool
+}
+
+func (l *Lock) Id() int {
+	return l.id
+}
+
+// Holder returns the context holding the lock, or -1 if the lock is free.
+func (l *Lock) Holder() int {
+	return l.holder
+}
+
+// Since returns the cycle the lock was acquired by the holder.
+func (l *Lock) Since() int {
+	return l.since
+}
+
+// Waiting returns the contexts waiting to acquire the lock, in the order they requested it.
+func (l *Lock) Waiting() []int {
+	return l.waiting
+}
+
+func (l *Lock) IsWaiting(context int) bool {
+	for _, w := range l.waiting {
+		if w == context {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lock) remove(index int) int {
+	context := l.waiting[index]
+	l.waiting = append(l.waiting[:index], l.waiting[index+1:]...)
+	return context
+}
+
+// next removes the context to be granted the lock from the waiting contexts
+func (l *Lock) next(policy flamego.LockPolicy, count int) int {
+	switch policy {
+	case flamego.LockPolicyRoundRobin:
+		best := 0
+		for i, w := range l.waiting {
+			if distance(l.previous, w, count) < distance(l.previous, l.waiting[best], count) {
+				best = i
+			}
+		}
+		return l.remove(best)
+	default:
+		return l.remove(0)
+	}
+}
+
+// distance from the previous holder to the context, wrapping around the given number of contexts
+func distance(previous, context, count int) int {
+	d := context - previous
+	if d <= 0 {
+		d += count
+	}
+	return d
+}
+
+func (p *Processor) Lock(id int) *Lock {
+	return p.locks[id]
+}
+
+func (p *Processor) LockPolicy() flamego.LockPolicy {
+	return p.lockPolicy
+}
+
+func (p *Processor) SetLockPolicy(policy flamego.LockPolicy) {
+	p.lockPolicy = policy
+}
+
+// LockThreshold returns the number of cycles a lock can be held before a diagnostic is logged.
+func (p *Processor) LockThreshold() int {
+	return p.lockThreshold
+}
+
+func (p *Processor) SetLockThreshold(threshold int) {
+	p.lockThreshold = threshold
+}
+
+// waitingFor returns the lock the context is waiting to acquire, or nil if there is none
+func (p *Processor) waitingFor(context int) *Lock {
+	for _, l := range p.locks {
+		if l.IsWaiting(context) {
+			return l
+		}
+	}
+	return nil
+}
+
+// RequireLock records whether the context requires the lock, to be arbitrated at the end of the cycle.
+func (p *Processor) RequireLock(context, lock int, required bool) {
+	if required {
+		p.requiredLocks[context] |= 1 << lock
+		p.requested[context/flamego.ContextCount] |= 1 << lock
+	} else {
+		p.requiredLocks[context] &^= 1 << lock
+	}
+}
+
+func (p *Processor) updateLocks(cycle int) {
+	// Gather requests made by each core
+	for i, r := range p.requested {
+		p.activeLocks |= r
+		p.requested[i] = 0
+	}
+	if p.activeLocks == 0 {
+		return
+	}
+	count := len(p.cores) * flamego.ContextCount
+
+	for m := p.activeLocks; m != 0; m &= m - 1 {
+		l := p.locks[bits.TrailingZeros64(m)]
+		bit := uint64(1) << l.id
+		// Release lock no longer required by holder
+		if l.holder != -1 && p.requiredLocks[l.holder]&bit == 0 {
+			p.context(l.holder).SetAcquiredLock(l.id, false)
+			l.previous = l.holder
+			l.holder = -1
+		}
+		// Remove contexts no longer waiting
+		for i := 0; i < len(l.waiting); {
+			if p.requiredLocks[l.waiting[i]]&bit == 0 {
+				l.remove(i)
+			} else {
+				i++
+			}
+		}
+	}
+
+	// Queue new requests, starting with a different context each cycle so simultaneous requests are not biased
+	for i := 0; i < count; i++ {
+		id := (cycle + i) % count
+		for m := p.requiredLocks[id]; m != 0; m &= m - 1 {
+			l := p.locks[bits.TrailingZeros64(m)]
+			if l.holder != id && !l.IsWaiting(id) {
+				l.waiting = append(l.waiting, id)
+				p.detectDeadlock(id)
+			}
+		}
+	}
+
+	for m := p.activeLocks; m != 0; m &= m - 1 {
+		l := p.locks[bits.TrailingZeros64(m)]
+		if l.holder == -1 {
+			if len(l.waiting) == 0 {
+				// Lock no longer active
+				p.activeLocks &^= 1 << l.id
+				continue
+			}
+			// Grant free lock
+			next := l.next(p.lockPolicy, count)
+			p.context(next).SetAcquiredLock(l.id, true)
+			l.holder = next
+			l.since = cycle
+			l.warned = false
+		} else if !l.warned && p.lockThreshold > 0 && cycle-l.since >= p.lockThreshold {
+			log.Println("Lock", l.id, "held by context", l.holder, "for", cycle-l.since, "cycles, waiting", l.waiting)
+			l.warned = true
+		}
+	}
+}
+
+// detectDeadlock follows the locks the context is waiting for and logs a cycle if it returns to the context
+func (p *Processor) detectDeadlock(context int) {
+	var chain []string
+	for c, visited := context, 0; visited < len(p.locks); visited++ {
+		l := p.waitingFor(c)
+		if l == nil || l.holder == -1 {
+			return
+		}
+		chain = append(chain, fmt.Sprintf("context %d waits for lock %d held by context %d", c, l.id, l.holder))
+		if l.holder == context {
+			log.Println("Deadlock:", strings.Join(chain, ", "))
+			return
+		}
+		c = l.holder
+	}
+}
@@ -0,0 +1,117 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProcessor_Lock(t *testing.T) {
+	for name, tt := range map[string]struct {
+		policy flamego.LockPolicy
+		order  []int
+	}{
+		"FIFO": {
+			policy: flamego.LockPolicyFIFO,
+			order:  []int{5, 3, 1, 6},
+		},
+		"RoundRobin": {
+			policy: flamego.LockPolicyRoundRobin,
+			order:  []int{5, 6, 1, 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			processor := newProcessor(vm.NewMemory(MemorySize))
+			processor.SetLockPolicy(tt.policy)
+			cycle := 0
+			for _, c := range []int{5, 3, 1, 6} {
+				x := processor.Core(0).Context(c)
+				x.SetInterrupted(true)
+				x.SetRequiresLock(7, true)
+				processor.Clock(cycle)
+				cycle++
+			}
+			lock := processor.Lock(7)
+			for _, c := range tt.order {
+				x := processor.Core(0).Context(c)
+				assert.Equal(t, c, lock.Holder())
+				assert.True(t, x.AcquiredLock(7))
+				assert.False(t, lock.IsWaiting(c))
+				// Other locks are unaffected
+				assert.Equal(t, -1, processor.Lock(6).Holder())
+				x.SetRequiresLock(7, false)
+				processor.Clock(cycle)
+				cycle++
+				assert.False(t, x.AcquiredLock(7))
+			}
+			assert.Equal(t, -1, lock.Holder())
+			assert.Empty(t, lock.Waiting())
+		})
+	}
+}
+
+func TestProcessor_LockThreshold(t *testing.T) {
+	var buffer bytes.Buffer
+	log.SetOutput(&buffer)
+	defer log.SetOutput(os.Stderr)
+
+	processor := newProcessor(vm.NewMemory(MemorySize))
+	processor.SetLockThreshold(10)
+	x := processor.Core(0).Context(2)
+	x.SetInterrupted(true)
+	x.SetRequiresLock(0, true)
+	for cycle := 0; cycle < 10; cycle++ {
+		processor.Clock(cycle)
+	}
+	assert.Empty(t, buffer.String())
+	processor.Clock(10)
+	assert.Contains(t, buffer.String(), "Lock 0 held by context 2 for 10 cycles")
+}
+
+func TestProcessor_LockDeadlock(t *testing.T) {
+	var buffer bytes.Buffer
+	log.SetOutput(&buffer)
+	defer log.SetOutput(os.Stderr)
+
+	processor := newProcessor(vm.NewMemory(MemorySize))
+	a := processor.Core(0).Context(1)
+	b := processor.Core(0).Context(2)
+	a.SetInterrupted(true)
+	b.SetInterrupted(true)
+	a.SetRequiresLock(0, true)
+	b.SetRequiresLock(1, true)
+	processor.Clock(0)
+	assert.True(t, a.AcquiredLock(0))
+	assert.True(t, b.AcquiredLock(1))
+
+	a.SetRequiresLock(1, true)
+	processor.Clock(1)
+	assert.Empty(t, buffer.String())
+
+	b.SetRequiresLock(0, true)
+	processor.Clock(2)
+	assert.Contains(t, buffer.String(), "Deadlock: context 2 waits for lock 0 held by context 1, context 1 waits for lock 1 held by context 2")
+}
+
+func TestMachine_UnknownLock(t *testing.T) {
+	// Unknown locks are ignored, so the program continues to acquire and release a known lock
+	m := runMachine(t, vm.Config{}, strings.NewReader(`
+loadc 100 r16
+lock r16
+unlock r16
+loadc 7 r17
+lock r17
+unlock r17
+loadc 1 r18
+halt
+`))
+	x := m.Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(1), x.ReadRegister(flamego.R18))
+	assert.Equal(t, -1, m.Processor.Lock(7).Holder())
+	assert.False(t, x.AcquiredLock(7))
+}
@@ -2,34 +2,138 @@ package vm
 
 import (
 	"aletheiaware.com/flamego"
+	"encoding/binary"
 	"log"
 )
 
 type Machine struct {
 	Processor *Processor
 	Memory    *Memory
+	// Controller shares memory between the L3 cache, on its CPU port, and devices, on DMA ports
+	Controller *MemoryController
+	// MemoryMap is checked by the reads and writes of each context, and initially maps all memory as RAM
+	MemoryMap *MemoryMap
 
 	Tick int
+
+	// FastForward skips cycles in which the machine is quiescent
+	FastForward bool
 }
 
 func NewMachine() *Machine {
-	memory := NewMemory(flamego.SizeMemory)
-	l3Cache := NewL3Cache(flamego.SizeL3Cache, memory)
+	return NewMachineWithConfig(Config{})
+}
+
+func NewMachineWithConfig(config Config) *Machine {
+	size := config.MemorySize
+	if size == 0 {
+		size = flamego.SizeMemory
+	}
+	memory := NewMemory(size)
+	memory.SetBurst(config.Burst)
+	controller := NewMemoryController(memory)
+	controller.SetArbitration(config.Arbitration)
+	memoryMap := NewMemoryMap(size)
+	l3Cache := NewL3Cache(flamego.SizeL3Cache, controller.AddPort(PortCPU))
+	l3Cache.SetBurst(config.Burst)
+	l3Cache.SetPrefetcher(config.Prefetch)
+	l3Cache.SetWritePolicy(config.Write[2])
 	processor := NewProcessor(l3Cache, memory)
+	processor.SetMemoryController(controller)
+	processor.SetParallel(config.Parallel)
 	for i := 0; i < flamego.CoreCount; i++ {
 		l2Cache := NewL2Cache(flamego.SizeL2Cache, l3Cache)
-		core := NewCore(i, processor, l2Cache)
+		l2Cache.SetBurst(config.Burst)
+		l2Cache.SetPrefetcher(config.Prefetch)
+		l2Cache.SetWritePolicy(config.Write[1])
+		var core flamego.Core
+		contexts := flamego.ContextCount
+		switch config.Core {
+		case CorePipelined:
+			core = NewPipelinedCore(i, processor, l2Cache)
+			contexts = 1
+		default:
+			c := NewCore(i, processor, l2Cache)
+			c.SetIssuePolicy(config.Issue)
+			core = c
+		}
 		processor.AddCore(core)
-		for j := 0; j < flamego.ContextCount; j++ {
+		for j := 0; j < contexts; j++ {
 			l1ICache := NewL1Cache(flamego.SizeL1Cache, l2Cache)
 			l1DCache := NewL1Cache(flamego.SizeL1Cache, l2Cache)
-			core.AddContext(NewContext(j, core, l1ICache, l1DCache))
+			l1ICache.SetBurst(config.Burst)
+			l1DCache.SetBurst(config.Burst)
+			l1ICache.SetPrefetcher(config.Prefetch)
+			l1DCache.SetPrefetcher(config.Prefetch)
+			l1ICache.SetWritePolicy(config.Write[0])
+			l1DCache.SetWritePolicy(config.Write[0])
+			x := NewContext(j, core, l1ICache, l1DCache)
+			x.SetMemoryMap(memoryMap)
+			core.AddContext(x)
+		}
+		if c, ok := core.(*Core); ok {
+			for j, w := range config.Weights {
+				c.SetWeight(j, w)
+			}
 		}
 	}
 	return &Machine{
-		Processor: processor,
-		Memory:    memory,
+		Processor:  processor,
+		Memory:     memory,
+		Controller: controller,
+		MemoryMap:  memoryMap,
+	}
+}
+
+// Boot writes the device table, and signals the first context of the first core with the address of the table in DeviceTableRegister.
+func (m *Machine) Boot() {
+	address := m.WriteDeviceTable()
+	m.Processor.Core(0).Context(0).WriteRegister(flamego.DeviceTableRegister, address)
+	m.Processor.Signal(flamego.InterruptSourceHost, 0)
+}
+
+// WriteDeviceTable writes the table of the interrupt controller and every device to the end of memory, returning its address.
+// The table is mapped as ROM, so stores by contexts fault rather than corrupt it.
+func (m *Machine) WriteDeviceTable() uint64 {
+	devices := m.Processor.Devices()
+	size := flamego.DeviceTableHeaderSize + (len(devices)+1)*flamego.DeviceTableEntrySize
+	buffer := make([]byte, size)
+	binary.BigEndian.PutUint64(buffer[0:], flamego.DeviceTableMagic)
+	binary.BigEndian.PutUint64(buffer[8:], uint64(len(devices)+1))
+	controller := m.Processor.controller
+	entry := func(index, id int, t flamego.DeviceType, controlBlock uint64) {
+		fields := [flamego.DeviceEntryFields]uint64{
+			uint64(id),
+			uint64(t),
+			controlBlock,
+			uint64(controller.Routed(id)),
+		}
+		offset := flamego.DeviceTableHeaderSize + index*flamego.DeviceTableEntrySize
+		for i, f := range fields {
+			binary.BigEndian.PutUint64(buffer[offset+i*flamego.DataSize:], f)
+		}
+	}
+	entry(0, flamego.InterruptControllerId, controller.Type(), controller.MemoryOffset())
+	for i, d := range devices {
+		var t flamego.DeviceType
+		var controlBlock uint64
+		if v, ok := d.(interface {
+			Type() flamego.DeviceType
+			MemoryOffset() uint64
+		}); ok {
+			t = v.Type()
+			controlBlock = v.MemoryOffset()
+		}
+		entry(i+1, flamego.CoreCount*flamego.ContextCount+i, t, controlBlock)
 	}
+	address := (m.Memory.Size() - uint64(size)) &^ (flamego.DataSize - 1)
+	m.Memory.Set(address, buffer)
+	m.MemoryMap.Add(Region{
+		Type:  RegionROM,
+		Start: address,
+		Size:  m.Memory.Size() - address,
+	})
+	return address
 }
 
 func (m *Machine) Clock() {
@@ -38,8 +142,11 @@ func (m *Machine) Clock() {
 		return
 	}
 
-	// Tick Processor
-	m.Processor.Clock(m.Tick)
+	if m.FastForward {
+		// Jump to the next event
+		m.Tick += m.Processor.FastForward(m.Tick)
+	}
 
-	m.Tick++
+	// Tick Processor, which clocks several cycles at once while its cores are clocked in parallel
+	m.Tick += m.Processor.Run(m.Tick)
 }
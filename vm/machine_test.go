@@ -0,0 +1,192 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/assembler"
+	"aletheiaware.com/flamego/vm"
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func BenchmarkMachine(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	for _, name := range []string{
+		"add",
+		"call",
+		"loop",
+		"mailbox",
+		"saverestore",
+		"spawn",
+		"storeandflush",
+	} {
+		b.Run(name, func(b *testing.B) {
+			f, err := os.Open(filepath.Join("..", "assembler", "samples", name+".fas"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+			a := assembler.NewAssembler()
+			if _, err := a.ReadFrom(f); err != nil {
+				b.Fatal(err)
+			}
+			var program bytes.Buffer
+			if _, err := a.WriteTo(&program); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				m := vm.NewMachine()
+				m.Memory.Set(0, program.Bytes())
+				m.Processor.Signal(flamego.InterruptSourceHost, 0)
+				b.StartTimer()
+				for !m.Processor.HasHalted() {
+					m.Clock()
+				}
+			}
+		})
+	}
+}
+
+func TestMachine_Bootloader(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "bootloader", "bootloader.fas"))
+	assert.NoError(t, err)
+	bootloader := assemble(t, bytes.NewReader(source))
+	// Bootloader reserves the control blocks of every device, ahead of its code
+	reserved := flamego.DeviceControlBlockCount * flamego.DeviceControlBlockSize
+	assert.Equal(t, make([]byte, reserved), bootloader[flamego.DeviceControlBlockAddress:flamego.DeviceControlBlockAddress+reserved])
+	// Bootloader ends with the start of the kernel
+	start := len(bootloader)
+
+	// Storage holds the magic number, and the kernel at the same address it is loaded into memory
+	image := make([]byte, start+1024)
+	binary.BigEndian.PutUint64(image, 0x123456789abcdef)
+	copy(image[start:], assemble(t, strings.NewReader(`
+load r30 0 r16
+load r30 8 r17
+halt
+`)))
+
+	m := vm.NewMachine()
+	storage := vm.NewFileStorage(m.Controller.AddPort(vm.PortDMA), flamego.DeviceControlBlockAddress)
+	storage.OpenMemory(image)
+	m.Processor.AddDevice(storage)
+	m.Memory.Set(0, bootloader)
+	m.Boot()
+	for !m.Processor.HasHalted() {
+		if m.Tick > 100000000 {
+			t.Fatal("Processor never halted")
+		}
+		m.Clock()
+	}
+	// Kernel reads the device table through the address left by the bootloader
+	context := m.Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(flamego.DeviceTableMagic), context.ReadRegister(flamego.R16))
+	assert.Equal(t, uint64(2), context.ReadRegister(flamego.R17))
+}
+
+func TestMachine_DeviceTable(t *testing.T) {
+	m := vm.NewMachine()
+	storage := vm.NewFileStorage(m.Controller.AddPort(vm.PortDMA), flamego.DeviceControlBlockAddress)
+	storage.OpenMemory(make([]byte, 1024))
+	m.Processor.AddDevice(storage)
+	timer := vm.NewTimer(m.Controller.AddPort(vm.PortDMA), flamego.DeviceControlBlockAddress+flamego.DeviceControlBlockSize)
+	m.Processor.AddDevice(timer)
+	m.Processor.InterruptController().(*vm.InterruptController).Route(65, 3)
+
+	// Boot context loads the count of entries from the table
+	m.Memory.Set(0, assemble(t, strings.NewReader(`
+load r30 8 r17
+halt
+`)))
+	m.Boot()
+	for !m.Processor.HasHalted() {
+		if m.Tick > 10000000 {
+			t.Fatal("Processor never halted")
+		}
+		m.Clock()
+	}
+	context := m.Processor.Core(0).Context(0)
+	address := context.ReadRegister(flamego.DeviceTableRegister)
+	assert.Equal(t, uint64(3), context.ReadRegister(flamego.R17))
+	assert.LessOrEqual(t, address+flamego.DeviceTableHeaderSize+3*flamego.DeviceTableEntrySize, m.Memory.Size())
+
+	// Table is reserved as ROM to the end of memory
+	region, ok := m.MemoryMap.Region(address, flamego.DeviceTableHeaderSize+3*flamego.DeviceTableEntrySize)
+	assert.True(t, ok)
+	assert.Equal(t, vm.RegionROM, region.Type)
+	assert.Equal(t, m.Memory.Size(), region.Start+region.Size)
+	region, ok = m.MemoryMap.Region(address-flamego.DataSize, flamego.DataSize)
+	assert.True(t, ok)
+	assert.Equal(t, vm.RegionRAM, region.Type)
+
+	table := m.Memory.Get(address, flamego.DeviceTableHeaderSize+3*flamego.DeviceTableEntrySize)
+	field := func(offset int) uint64 {
+		return binary.BigEndian.Uint64(table[offset*flamego.DataSize:])
+	}
+	assert.Equal(t, uint64(flamego.DeviceTableMagic), field(0))
+	assert.Equal(t, uint64(3), field(1))
+	for i, want := range [][flamego.DeviceEntryFields]uint64{
+		{flamego.InterruptControllerId, uint64(flamego.DeviceTypeInterruptController), flamego.InterruptControlBlockAddress, vm.InterruptRouteNone},
+		{64, uint64(flamego.DeviceTypeStorage), flamego.DeviceControlBlockAddress, vm.InterruptRouteNone},
+		{65, uint64(flamego.DeviceTypeTimer), flamego.DeviceControlBlockAddress + flamego.DeviceControlBlockSize, 3},
+	} {
+		for j, w := range want {
+			assert.Equal(t, w, field(2+i*int(flamego.DeviceEntryFields)+j))
+		}
+	}
+}
+
+func TestMachine_Timestamp(t *testing.T) {
+	m := vm.NewMachine()
+	m.Processor.SetEpoch(1000)
+	m.Memory.Set(0, assemble(t, strings.NewReader(`
+timestamp 0 r16
+timestamp 1 r17
+loadc 1 r20
+timestamp 1 r18
+timestamp 2 r19
+timestamp 0 r21
+halt
+`)))
+	m.Processor.Signal(flamego.InterruptSourceHost, 0)
+	for !m.Processor.HasHalted() {
+		if m.Tick > 10000000 {
+			t.Fatal("Processor never halted")
+		}
+		m.Clock()
+	}
+	context := m.Processor.Core(0).Context(0)
+	start := context.ReadRegister(flamego.R16)
+	end := context.ReadRegister(flamego.R21)
+	assert.Less(t, start, end)
+	assert.Less(t, end, uint64(m.Tick))
+	// Two instructions retired between the reads of the instruction counter
+	assert.Equal(t, uint64(2), context.ReadRegister(flamego.R18)-context.ReadRegister(flamego.R17))
+	assert.Less(t, context.ReadRegister(flamego.R18), context.RetiredInstructions())
+	time := context.ReadRegister(flamego.R19)
+	assert.Less(t, 1000+start*flamego.ClockPeriod, time)
+	assert.Less(t, time, 1000+end*flamego.ClockPeriod)
+}
+
+func TestMachine_SaveRestore(t *testing.T) {
+	m := runMachine(t, vm.Config{}, strings.NewReader(loadSamples(t, "saverestore")["saverestore"]))
+	context := m.Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(5), context.ReadRegister(flamego.R16))
+	assert.Equal(t, uint64(6), context.ReadRegister(flamego.RProcessIdentifier))
+	assert.Equal(t, uint64(6), context.ReadRegister(flamego.R17))
+	// Interrupt state is restored with the rest of the register file
+	assert.Equal(t, uint64(7), context.ReadRegister(flamego.RInterruptReturn))
+	assert.Equal(t, uint64(8), context.ReadRegister(flamego.RInterruptValue))
+	assert.Equal(t, uint64(7), context.ReadRegister(flamego.R18))
+	assert.Equal(t, uint64(8), context.ReadRegister(flamego.R19))
+}
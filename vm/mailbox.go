@@ -0,0 +1,57 @@
+package vm
+
+func NewMailbox(capacity int) *Mailbox {
+	return &Mailbox{
+		capacity: capacity,
+	}
+}
+
+// Mailbox is a bounded queue of messages sent to a context.
+type Mailbox struct {
+	capacity  int
+	messages  []uint64
+	overflows int
+}
+
+func (m *Mailbox) Capacity() int {
+	return m.capacity
+}
+
+func (m *Mailbox) Messages() []uint64 {
+	return m.messages
+}
+
+// Overflows is the number of messages rejected because the mailbox was full.
+func (m *Mailbox) Overflows() int {
+	return m.overflows
+}
+
+// Overflow counts a message rejected because the mailbox was full.
+func (m *Mailbox) Overflow() {
+	m.overflows++
+}
+
+func (m *Mailbox) IsEmpty() bool {
+	return len(m.messages) == 0
+}
+
+func (m *Mailbox) IsFull() bool {
+	return len(m.messages) >= m.capacity
+}
+
+func (m *Mailbox) Push(message uint64) bool {
+	if m.IsFull() {
+		return false
+	}
+	m.messages = append(m.messages, message)
+	return true
+}
+
+func (m *Mailbox) Pop() (uint64, bool) {
+	if m.IsEmpty() {
+		return 0, false
+	}
+	message := m.messages[0]
+	m.messages = m.messages[1:]
+	return message, true
+}
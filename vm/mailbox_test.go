@@ -0,0 +1,116 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMailbox(t *testing.T) {
+	mailbox := vm.NewMailbox(2)
+	assert.True(t, mailbox.IsEmpty())
+
+	_, ok := mailbox.Pop()
+	assert.False(t, ok)
+
+	assert.True(t, mailbox.Push(1))
+	assert.True(t, mailbox.Push(2))
+	assert.True(t, mailbox.IsFull())
+
+	// Overflowing messages are rejected
+	assert.False(t, mailbox.Push(3))
+
+	message, ok := mailbox.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), message)
+	message, ok = mailbox.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), message)
+	assert.True(t, mailbox.IsEmpty())
+}
+
+func TestProcessor_Send(t *testing.T) {
+	processor := newProcessor(vm.NewMemory(MemorySize))
+	context := processor.Core(0).Context(3).(*vm.Context)
+
+	// Waiting context is woken by delivery
+	context.Wait()
+	assert.True(t, context.IsAsleep())
+	assert.True(t, context.IsWaiting())
+	processor.Send(0, 3, 42)
+	assert.Equal(t, flamego.MessageDelivered, processor.Delivery(0))
+	assert.False(t, context.IsAsleep())
+	assert.False(t, context.IsWaiting())
+
+	message, ok := processor.Receive(3)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(42), message)
+	_, ok = processor.Receive(3)
+	assert.False(t, ok)
+
+	// Sleeping context is not woken by delivery
+	context.Sleep()
+	processor.Send(0, 3, 43)
+	assert.Equal(t, flamego.MessageDelivered, processor.Delivery(0))
+	assert.True(t, context.IsAsleep())
+
+	// Full mailbox rejects delivery
+	for i := 1; i < flamego.MailboxSize; i++ {
+		processor.Send(0, 3, uint64(i))
+		assert.Equal(t, flamego.MessageDelivered, processor.Delivery(0))
+	}
+	processor.Send(0, 3, 44)
+	assert.Equal(t, flamego.MessageRejected, processor.Delivery(0))
+	assert.Equal(t, 1, processor.Mailbox(3).Overflows())
+
+	// Retries of the rejected message are counted once
+	processor.Send(0, 3, 44)
+	assert.Equal(t, flamego.MessageRejected, processor.Delivery(0))
+	assert.Equal(t, 1, processor.Mailbox(3).Overflows())
+	processor.Receive(3)
+	processor.Send(0, 3, 44)
+	assert.Equal(t, flamego.MessageDelivered, processor.Delivery(0))
+	processor.Send(0, 3, 45)
+	assert.Equal(t, flamego.MessageRejected, processor.Delivery(0))
+	assert.Equal(t, 2, processor.Mailbox(3).Overflows())
+
+	// Identifiers without a context are undeliverable
+	processor.Send(0, -1, 46)
+	assert.Equal(t, flamego.MessageUndeliverable, processor.Delivery(0))
+	// Processor has a single core
+	processor.Send(0, flamego.ContextCount, 46)
+	assert.Equal(t, flamego.MessageUndeliverable, processor.Delivery(0))
+}
+
+func TestMachine_Send_Undeliverable(t *testing.T) {
+	for name, config := range map[string]vm.Config{
+		"Barrel":    {},
+		"Pipelined": {Core: vm.CorePipelined},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Boot context is interrupted, sends to identifiers which are not contexts are reported rather than raising an error
+			m := runMachine(t, config, strings.NewReader(`
+loadc 42 r17
+loadc 1000 r16
+send r16 r17 r18
+loadc 1 r16
+send r16 r17 r19
+send r0 r17 r20
+receive r21
+halt
+`))
+			x := m.Processor.Core(0).Context(0)
+			assert.Equal(t, uint64(0), x.ReadRegister(flamego.R18))
+			if config.Core == vm.CorePipelined {
+				// Pipelined cores have a single context
+				assert.Equal(t, uint64(0), x.ReadRegister(flamego.R19))
+			} else {
+				assert.Equal(t, uint64(1), x.ReadRegister(flamego.R19))
+			}
+			assert.Equal(t, uint64(1), x.ReadRegister(flamego.R20))
+			assert.Equal(t, uint64(42), x.ReadRegister(flamego.R21))
+		})
+	}
+}
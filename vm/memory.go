@@ -6,27 +6,33 @@ import (
 	"io"
 )
 
-func NewMemory(size int) *Memory {
+// Unit: Bytes
+const MemoryPageSize = 4 * flamego.KB
+
+// NewMemory returns memory of the given size, host memory is allocated for each page when it is first written.
+func NewMemory(size uint64) *Memory {
 	return &Memory{
 		size:   size,
-		bus:    NewBus(flamego.BusSize), // TODO consider a second bus for IO - DMA
-		data:   make([]byte, size),
+		bus:    NewBus(flamego.BusSize),
+		pages:  make(map[uint64][]byte),
 		isFree: true,
 	}
 }
 
 type Memory struct {
-	size         int
+	size         uint64
 	bus          *Bus
-	data         []byte
+	pages        map[uint64][]byte // Pages which have been written, by page number
 	address      uint64
 	isSuccessful bool
 	isBusy       bool
 	isFree       bool
 	operation    flamego.MemoryOperation
+	burst        Burst
+	remaining    int // Clocks before the current request completes
 }
 
-func (m *Memory) Size() int {
+func (m *Memory) Size() uint64 {
 	return m.size
 }
 
@@ -34,8 +40,20 @@ func (m *Memory) Bus() flamego.Bus {
 	return m.bus
 }
 
-func (m *Memory) Data() []byte {
-	return m.data
+// Pages returns the number of pages allocated.
+func (m *Memory) Pages() int {
+	return len(m.pages)
+}
+
+// page returns the page holding the address, allocating it if requested, or nil if it has never been written.
+func (m *Memory) page(address uint64, allocate bool) []byte {
+	number := address / MemoryPageSize
+	p, ok := m.pages[number]
+	if !ok && allocate {
+		p = make([]byte, MemoryPageSize)
+		m.pages[number] = p
+	}
+	return p
 }
 
 func (m *Memory) Address() uint64 {
@@ -62,22 +80,33 @@ func (m *Memory) Operation() flamego.MemoryOperation {
 	return m.operation
 }
 
+// SetBurst sets the timing of burst transactions.
+func (m *Memory) SetBurst(burst Burst) {
+	m.burst = burst
+}
+
+func (m *Memory) Burst() Burst {
+	return m.burst
+}
+
 func (m *Memory) Read(address uint64) {
-	if address < 0 || address > uint64(m.size) {
-		panic("Memory access error")
-	}
-	if m.isBusy {
-		panic("Memory already busy")
-	}
-	m.isSuccessful = false
-	m.isBusy = true
-	m.isFree = false
-	m.operation = flamego.MemoryRead
-	m.address = address
+	m.request(flamego.MemoryRead, address, m.bus.Size(), 1)
 }
 
 func (m *Memory) Write(address uint64) {
-	if address < 0 || address > uint64(m.size) {
+	m.request(flamego.MemoryWrite, address, m.bus.Size(), 1)
+}
+
+func (m *Memory) ReadBurst(address uint64, length int) {
+	m.request(flamego.MemoryRead, address, length, m.burst.Clocks(length, m.bus.Size()))
+}
+
+func (m *Memory) WriteBurst(address uint64, length int) {
+	m.request(flamego.MemoryWrite, address, length, m.burst.Clocks(length, m.bus.Size()))
+}
+
+func (m *Memory) request(operation flamego.MemoryOperation, address uint64, length, clocks int) {
+	if end := address + uint64(length); end < address || end > m.size {
 		panic("Memory access error")
 	}
 	if m.isBusy {
@@ -86,21 +115,37 @@ func (m *Memory) Write(address uint64) {
 	m.isSuccessful = false
 	m.isBusy = true
 	m.isFree = false
-	m.operation = flamego.MemoryWrite
+	m.operation = operation
 	m.address = address
+	m.remaining = clocks
+	m.bus.SetLength(length)
 }
 
 func (m *Memory) Clock(cycle int) {
 	if m.isBusy {
-		for i := 0; i < m.bus.Size(); i++ {
+		if m.remaining--; m.remaining > 0 {
+			// Burst is still being transferred
+			return
+		}
+		var page []byte
+		for i := 0; i < m.bus.Length(); i++ {
+			address := m.address + uint64(i)
+			if page == nil || address%MemoryPageSize == 0 {
+				page = m.page(address, m.operation == flamego.MemoryWrite)
+			}
+			offset := address % MemoryPageSize
 			switch m.operation {
 			case flamego.MemoryNone:
 				// Do nothing
 			case flamego.MemoryRead:
-				m.bus.Write(i, m.data[m.address+uint64(i)])
+				var value byte
+				if page != nil {
+					value = page[offset]
+				}
+				m.bus.Write(i, value)
 			case flamego.MemoryWrite:
 				if m.bus.IsValid(i) && m.bus.IsDirty(i) {
-					m.data[m.address+uint64(i)] = m.bus.Read(i)
+					page[offset] = m.bus.Read(i)
 				}
 			default:
 				panic(fmt.Errorf("Unrecognized Memory Operation: %v", m.operation))
@@ -118,11 +163,38 @@ func (m *Memory) Load(r io.Reader) (int, error) { //heysup? like ketchup, but ma
 	if err != nil {
 		return 0, err
 	}
-	return copy(m.data, d), nil
+	if uint64(len(d)) > m.size {
+		d = d[:m.size]
+	}
+	m.Set(0, d)
+	return len(d), nil
 }
 
 func (m *Memory) Set(address uint64, data []byte) {
-	for i := 0; i < len(data); i++ {
-		m.data[address+uint64(i)] = data[i]
+	if end := address + uint64(len(data)); end < address || end > m.size {
+		panic("Memory access error")
+	}
+	for i := 0; i < len(data); {
+		a := address + uint64(i)
+		n := copy(m.page(a, true)[a%MemoryPageSize:], data[i:])
+		i += n
+	}
+}
+
+// Get returns a copy of the given number of bytes from the address, untouched pages read as zero.
+func (m *Memory) Get(address uint64, length int) []byte {
+	data := make([]byte, length)
+	for i := 0; i < length; {
+		a := address + uint64(i)
+		offset := a % MemoryPageSize
+		n := int(MemoryPageSize - offset)
+		if n > length-i {
+			n = length - i
+		}
+		if p := m.page(a, false); p != nil {
+			copy(data[i:i+n], p[offset:])
+		}
+		i += n
 	}
+	return data
 }
@@ -0,0 +1,66 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMemory_Sparse(t *testing.T) {
+	size := uint64(16 * flamego.GB)
+	memory := vm.NewMemory(size)
+	assert.Equal(t, size, memory.Size())
+	assert.Equal(t, 0, memory.Pages())
+
+	// Reading an untouched page doesn't allocate it
+	address := size - flamego.DataSize
+	memory.Read(address)
+	memory.Clock(0)
+	assert.True(t, memory.IsSuccessful())
+	for i := 0; i < flamego.DataSize; i++ {
+		assert.Equal(t, byte(0), memory.Bus().Read(i))
+	}
+	memory.Free()
+	assert.Equal(t, 0, memory.Pages())
+
+	// Writing allocates the page
+	bus := memory.Bus()
+	for i := 0; i < flamego.DataSize; i++ {
+		bus.Write(i, byte(i+1))
+	}
+	memory.Write(address)
+	memory.Clock(0)
+	memory.Free()
+	assert.Equal(t, 1, memory.Pages())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, memory.Get(address, flamego.DataSize))
+
+	// Values span two pages
+	memory.Set(8*flamego.GB-4, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	assert.Equal(t, 3, memory.Pages())
+	assert.Equal(t, []byte{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0}, memory.Get(8*flamego.GB-6, 12))
+
+	assert.Panics(t, func() {
+		memory.Read(size)
+	})
+	assert.Panics(t, func() {
+		memory.Set(size-4, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	})
+}
+
+func TestMemory_Machine(t *testing.T) {
+	f := strings.NewReader(`
+loadc 7 r16
+store r0 #Value r16
+flush r0 #Value
+halt
+#Value
+data 0
+`)
+	m := runMachine(t, vm.Config{MemorySize: 4 * flamego.GB}, f)
+	assert.Equal(t, uint64(4*flamego.GB), m.Memory.Size())
+	// Only the pages of the program are allocated
+	assert.Equal(t, 1, m.Memory.Pages())
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 7}, m.Memory.Get(4*flamego.InstructionSize, flamego.DataSize))
+}
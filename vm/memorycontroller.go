@@ -0,0 +1,304 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+// PortType identifies the side of the memory controller a port serves.
+type PortType int
+
+const (
+	// Serves the caches
+	PortCPU PortType = iota
+	// Serves devices transferring directly to and from memory
+	PortDMA
+)
+
+func (t PortType) String() string {
+	switch t {
+	case PortCPU:
+		return "CPU"
+	case PortDMA:
+		return "DMA"
+	}
+	return "unknown"
+}
+
+// Arbitration selects the port whose request is served next when several are waiting.
+type Arbitration int
+
+const (
+	// Ports are served in turn
+	ArbitrateRoundRobin Arbitration = iota
+	// CPU ports are served before DMA ports, which are served in turn
+	ArbitrateCPU
+	// DMA ports are served in turn before CPU ports
+	ArbitrateDMA
+)
+
+func (a Arbitration) String() string {
+	switch a {
+	case ArbitrateRoundRobin:
+		return "roundrobin"
+	case ArbitrateCPU:
+		return "cpu"
+	case ArbitrateDMA:
+		return "dma"
+	}
+	return "unknown"
+}
+
+func ParseArbitration(s string) (Arbitration, error) {
+	switch s {
+	case "roundrobin":
+		return ArbitrateRoundRobin, nil
+	case "cpu":
+		return ArbitrateCPU, nil
+	case "dma":
+		return ArbitrateDMA, nil
+	}
+	return 0, fmt.Errorf("Unrecognized Arbitration: %s", s)
+}
+
+// NewMemoryController returns a controller sharing the memory between ports, each with its own bus.
+func NewMemoryController(memory *Memory) *MemoryController {
+	return &MemoryController{
+		memory: memory,
+		last:   -1,
+	}
+}
+
+// MemoryController serves one request at a time, from the ports which have a request waiting, chosen by its arbitration.
+type MemoryController struct {
+	memory      *Memory
+	arbitration Arbitration
+	ports       []*MemoryPort
+	granted     *MemoryPort // Port whose request memory is serving
+	last        int         // Index of the port granted last, turns start with the port after it
+}
+
+func (c *MemoryController) Memory() *Memory {
+	return c.memory
+}
+
+// SetArbitration sets how the controller chooses between waiting requests.
+func (c *MemoryController) SetArbitration(arbitration Arbitration) {
+	c.arbitration = arbitration
+}
+
+func (c *MemoryController) Arbitration() Arbitration {
+	return c.arbitration
+}
+
+// AddPort returns a new port of the given type.
+func (c *MemoryController) AddPort(t PortType) *MemoryPort {
+	p := &MemoryPort{
+		controller: c,
+		index:      len(c.ports),
+		portType:   t,
+		bus:        NewBus(c.memory.Bus().Size()),
+		isFree:     true,
+	}
+	c.ports = append(c.ports, p)
+	return p
+}
+
+func (c *MemoryController) Ports() []*MemoryPort {
+	return c.ports
+}
+
+// IsBusy returns true while memory is serving a request, or any request is waiting.
+func (c *MemoryController) IsBusy() bool {
+	if c.granted != nil {
+		return true
+	}
+	for _, p := range c.ports {
+		if p.isWaiting {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MemoryController) Clock(cycle int) {
+	c.memory.Clock(cycle)
+	if c.granted != nil && !c.memory.IsBusy() {
+		c.complete()
+	}
+	for _, p := range c.ports {
+		if p.isWaiting {
+			p.statistics.Waits++
+		}
+	}
+	c.arbitrate()
+}
+
+// arbitrate grants memory to the next waiting request, if memory is idle.
+func (c *MemoryController) arbitrate() {
+	if c.granted != nil || c.memory.IsBusy() || !c.memory.IsFree() {
+		return
+	}
+	var next *MemoryPort
+	for i := range c.ports {
+		// Take turns starting after the port granted last
+		p := c.ports[(c.last+1+i)%len(c.ports)]
+		if !p.isWaiting {
+			continue
+		}
+		if next == nil || c.precedes(p, next) {
+			next = p
+		}
+	}
+	if next != nil {
+		c.grant(next)
+	}
+}
+
+// precedes returns true if the port's requests are served before those of the other port.
+func (c *MemoryController) precedes(port, other *MemoryPort) bool {
+	switch c.arbitration {
+	case ArbitrateCPU:
+		return port.portType == PortCPU && other.portType != PortCPU
+	case ArbitrateDMA:
+		return port.portType == PortDMA && other.portType != PortDMA
+	}
+	return false
+}
+
+// grant copies the request from the port to memory.
+func (c *MemoryController) grant(p *MemoryPort) {
+	p.isWaiting = false
+	c.granted = p
+	c.last = p.index
+	mb := c.memory.Bus()
+	switch {
+	case p.operation == flamego.MemoryRead && p.length == mb.Size():
+		c.memory.Read(p.address)
+	case p.operation == flamego.MemoryRead:
+		c.memory.ReadBurst(p.address, p.length)
+	case p.length == mb.Size():
+		c.memory.Write(p.address)
+	default:
+		c.memory.WriteBurst(p.address, p.length)
+	}
+	if p.operation == flamego.MemoryWrite {
+		for i := 0; i < p.length; i++ {
+			if p.bus.IsValid(i) {
+				mb.Write(i, p.bus.Read(i))
+				mb.SetDirty(i, p.bus.IsDirty(i))
+			} else {
+				mb.SetValid(i, false)
+			}
+		}
+	}
+}
+
+// complete copies the result of the granted request from memory to the port, and frees memory for the next request.
+func (c *MemoryController) complete() {
+	p := c.granted
+	mb := c.memory.Bus()
+	for i := 0; i < p.length; i++ {
+		if p.operation == flamego.MemoryRead {
+			p.bus.Write(i, mb.Read(i))
+		}
+		p.bus.SetDirty(i, false)
+	}
+	p.isSuccessful = c.memory.IsSuccessful()
+	p.isBusy = false
+	c.memory.Free()
+	c.granted = nil
+}
+
+// MemoryPort is a store which requests memory through the controller, transferring data on its own bus.
+type MemoryPort struct {
+	controller   *MemoryController
+	index        int
+	portType     PortType
+	bus          *Bus
+	address      uint64
+	length       int
+	operation    flamego.MemoryOperation
+	isWaiting    bool // Whether the request is waiting to be granted
+	isBusy       bool
+	isFree       bool
+	isSuccessful bool
+	statistics   PortStatistics
+}
+
+type PortStatistics struct {
+	Requests int
+	Waits    int // Memory clocks requests waited to be granted
+}
+
+func (p *MemoryPort) Type() PortType {
+	return p.portType
+}
+
+func (p *MemoryPort) Statistics() PortStatistics {
+	return p.statistics
+}
+
+// Size returns the number of bytes of memory reached through the port.
+func (p *MemoryPort) Size() uint64 {
+	return p.controller.memory.Size()
+}
+
+func (p *MemoryPort) Bus() flamego.Bus {
+	return p.bus
+}
+
+func (p *MemoryPort) IsBusy() bool {
+	return p.isBusy
+}
+
+func (p *MemoryPort) IsFree() bool {
+	return p.isFree
+}
+
+func (p *MemoryPort) Free() {
+	p.isFree = true
+}
+
+func (p *MemoryPort) IsSuccessful() bool {
+	return p.isSuccessful
+}
+
+// Clock does nothing as ports are served when the controller is clocked.
+func (p *MemoryPort) Clock(cycle int) {}
+
+func (p *MemoryPort) Read(address uint64) {
+	p.request(flamego.MemoryRead, address, p.bus.Size())
+}
+
+func (p *MemoryPort) Write(address uint64) {
+	p.request(flamego.MemoryWrite, address, p.bus.Size())
+}
+
+func (p *MemoryPort) ReadBurst(address uint64, length int) {
+	p.request(flamego.MemoryRead, address, length)
+}
+
+func (p *MemoryPort) WriteBurst(address uint64, length int) {
+	p.request(flamego.MemoryWrite, address, length)
+}
+
+func (p *MemoryPort) request(operation flamego.MemoryOperation, address uint64, length int) {
+	if end := address + uint64(length); end < address || end > p.controller.memory.Size() {
+		panic("Memory access error")
+	}
+	if p.isBusy {
+		panic("Memory port already busy")
+	}
+	p.isSuccessful = false
+	p.isBusy = true
+	p.isFree = false
+	p.isWaiting = true
+	p.operation = operation
+	p.address = address
+	p.length = length
+	p.bus.SetLength(length)
+	p.statistics.Requests++
+	// Requests made while memory is idle are served without waiting for the controller's next clock
+	p.controller.arbitrate()
+}
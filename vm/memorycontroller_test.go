@@ -0,0 +1,193 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func writePort(port *vm.MemoryPort, address uint64, value byte) {
+	bus := port.Bus()
+	for i := 0; i < bus.Size(); i++ {
+		bus.Write(i, value)
+	}
+	port.Write(address)
+}
+
+func readPort(port *vm.MemoryPort) []byte {
+	bus := port.Bus()
+	data := make([]byte, bus.Length())
+	for i := range data {
+		data[i] = bus.Read(i)
+	}
+	return data
+}
+
+// clockUntilServed clocks the controller until the port's request completes, returning the number of clocks.
+func clockUntilServed(t *testing.T, controller *vm.MemoryController, port *vm.MemoryPort) int {
+	t.Helper()
+	for i := 1; i <= 100; i++ {
+		controller.Clock(i)
+		if !port.IsBusy() {
+			assert.True(t, port.IsSuccessful())
+			port.Free()
+			return i
+		}
+	}
+	t.Fatal("Request not served")
+	return 0
+}
+
+func TestMemoryController_Ports(t *testing.T) {
+	memory := vm.NewMemory(flamego.KB)
+	memory.Set(0, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	controller := vm.NewMemoryController(memory)
+	cpu := controller.AddPort(vm.PortCPU)
+	dma := controller.AddPort(vm.PortDMA)
+	assert.False(t, controller.IsBusy())
+
+	// Both ports request at once, each on its own bus
+	cpu.Read(0)
+	writePort(dma, 64, 9)
+	assert.True(t, cpu.IsBusy())
+	assert.True(t, dma.IsBusy())
+	assert.False(t, cpu.IsFree())
+
+	assert.Equal(t, 1, clockUntilServed(t, controller, cpu))
+	assert.True(t, dma.IsBusy())
+	assert.Equal(t, 1, clockUntilServed(t, controller, dma))
+	assert.False(t, controller.IsBusy())
+
+	// Read wasn't corrupted by the write
+	size := cpu.Bus().Size()
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}[:size], readPort(cpu))
+	for _, b := range memory.Get(64, size) {
+		assert.Equal(t, byte(9), b)
+	}
+	assert.Equal(t, 1, cpu.Statistics().Requests)
+	assert.Equal(t, 1, dma.Statistics().Waits)
+
+	// Busy port cannot make another request
+	cpu.Read(0)
+	assert.Panics(t, func() {
+		cpu.Read(0)
+	})
+	assert.Panics(t, func() {
+		dma.Read(flamego.KB)
+	})
+}
+
+func TestMemoryController_Burst(t *testing.T) {
+	memory := vm.NewMemory(flamego.KB)
+	memory.SetBurst(vm.Burst{
+		Latency:   2,
+		Bandwidth: 8,
+	})
+	memory.Set(32, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	controller := vm.NewMemoryController(memory)
+	dma := controller.AddPort(vm.PortDMA)
+	dma.ReadBurst(32, 16)
+	clockUntilServed(t, controller, dma)
+	assert.Equal(t, 16, dma.Bus().Length())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, readPort(dma))
+}
+
+func TestMemoryController_Arbitration(t *testing.T) {
+	for name, tt := range map[string]struct {
+		arbitration vm.Arbitration
+		order       []int
+	}{
+		"RoundRobin": {
+			arbitration: vm.ArbitrateRoundRobin,
+			order:       []int{0, 1, 2, 3},
+		},
+		"CPU": {
+			arbitration: vm.ArbitrateCPU,
+			order:       []int{0, 2, 3, 1},
+		},
+		"DMA": {
+			arbitration: vm.ArbitrateDMA,
+			order:       []int{0, 1, 3, 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			memory := vm.NewMemory(flamego.KB)
+			controller := vm.NewMemoryController(memory)
+			controller.SetArbitration(tt.arbitration)
+			ports := []*vm.MemoryPort{
+				controller.AddPort(vm.PortCPU),
+				controller.AddPort(vm.PortDMA),
+				controller.AddPort(vm.PortCPU),
+				controller.AddPort(vm.PortDMA),
+			}
+			// First request is granted immediately, the rest wait
+			for i, p := range ports {
+				p.Read(uint64(i * 8))
+			}
+			var order []int
+			for cycle := 1; len(order) < len(ports); cycle++ {
+				controller.Clock(cycle)
+				for i, p := range ports {
+					if !p.IsBusy() && !p.IsFree() {
+						order = append(order, i)
+						p.Free()
+					}
+				}
+			}
+			assert.Equal(t, tt.order, order)
+		})
+	}
+}
+
+func TestMemoryController_Starvation(t *testing.T) {
+	for name, tt := range map[string]struct {
+		arbitration vm.Arbitration
+		served      bool
+	}{
+		"RoundRobin": {
+			arbitration: vm.ArbitrateRoundRobin,
+			served:      true,
+		},
+		"CPU": {
+			arbitration: vm.ArbitrateCPU,
+		},
+		"DMA": {
+			arbitration: vm.ArbitrateDMA,
+			served:      true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			memory := vm.NewMemory(flamego.KB)
+			controller := vm.NewMemoryController(memory)
+			controller.SetArbitration(tt.arbitration)
+			cpu1 := controller.AddPort(vm.PortCPU)
+			cpu2 := controller.AddPort(vm.PortCPU)
+			dma := controller.AddPort(vm.PortDMA)
+			cpu1.Read(0)
+			cpu2.Read(8)
+			dma.Read(16)
+			// CPU ports request again as soon as each request is served
+			for cycle := 1; cycle <= 10; cycle++ {
+				controller.Clock(cycle)
+				for _, p := range []*vm.MemoryPort{cpu1, cpu2} {
+					if !p.IsBusy() {
+						p.Free()
+						p.Read(0)
+					}
+				}
+			}
+			assert.Equal(t, tt.served, !dma.IsBusy())
+		})
+	}
+}
+
+func TestParseArbitration(t *testing.T) {
+	for _, a := range []vm.Arbitration{vm.ArbitrateRoundRobin, vm.ArbitrateCPU, vm.ArbitrateDMA} {
+		p, err := vm.ParseArbitration(a.String())
+		assert.Nil(t, err)
+		assert.Equal(t, a, p)
+	}
+	_, err := vm.ParseArbitration("fifo")
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"encoding/binary"
+	"fmt"
+)
+
+// RegionType selects how the processor accesses a region of the memory map.
+type RegionType int
+
+const (
+	// Read and written through the caches
+	RegionRAM RegionType = iota
+	// Read through the caches, writes fault
+	RegionROM
+	// Read and written by a device, bypassing the caches
+	RegionMMIO
+)
+
+func (t RegionType) String() string {
+	switch t {
+	case RegionRAM:
+		return "RAM"
+	case RegionROM:
+		return "ROM"
+	case RegionMMIO:
+		return "MMIO"
+	}
+	return "unknown"
+}
+
+// MMIO is a device with registers read and written through a window of the memory map.
+// Registers are DataSize bytes, and are addressed by their offset from the start of the window.
+// Cores clocked in parallel read registers concurrently, so reads must not change the device, writes take effect at the end of the cycle.
+type MMIO interface {
+	ReadMMIO(uint64) uint64
+	WriteMMIO(uint64, uint64)
+}
+
+// Region is a range of addresses of the memory map.
+type Region struct {
+	Type   RegionType
+	Start  uint64
+	Size   uint64
+	Device MMIO // Serves the reads and writes of a MMIO region
+}
+
+// Contains returns true if the region holds the given number of bytes from the address.
+func (r Region) Contains(address uint64, length int) bool {
+	end := address + uint64(length)
+	return address >= r.Start && end >= address && end <= r.Start+r.Size
+}
+
+// NewMemoryMap returns a memory map with a single RAM region of the given size.
+func NewMemoryMap(size uint64) *MemoryMap {
+	m := &MemoryMap{}
+	m.Add(Region{
+		Type: RegionRAM,
+		Size: size,
+	})
+	return m
+}
+
+// MemoryMap describes the regions of the address space seen by the processor, addresses outside every region fault.
+type MemoryMap struct {
+	regions []Region
+}
+
+// Add maps the region, over any regions added before it.
+func (m *MemoryMap) Add(region Region) {
+	if region.Size == 0 || region.Start+region.Size < region.Start {
+		panic(fmt.Errorf("Invalid Memory Region: %s %d %d", region.Type, region.Start, region.Size))
+	}
+	if region.Type == RegionMMIO && region.Device == nil {
+		panic("MMIO region without device")
+	}
+	m.regions = append(m.regions, region)
+}
+
+func (m *MemoryMap) Regions() []Region {
+	return m.regions
+}
+
+// Region returns the region holding the given number of bytes from the address, and false if the bytes are not all in the same region.
+func (m *MemoryMap) Region(address uint64, length int) (Region, bool) {
+	end := address + uint64(length)
+	overlapped := false
+	for i := len(m.regions) - 1; i >= 0; i-- {
+		r := m.regions[i]
+		if r.Contains(address, 1) {
+			// Latest region mapping the address, which must not be mapped over by a later region
+			return r, !overlapped && r.Contains(address, length)
+		}
+		if r.Start < end && address < r.Start+r.Size {
+			overlapped = true
+		}
+	}
+	return Region{}, false
+}
+
+// mappedCache checks each request of a context against the memory map, raising InterruptMemoryAccessError on faults,
+// which are unsuccessful so the instruction is abandoned for the interrupt, and serving MMIO requests without the cache.
+type mappedCache struct {
+	flamego.Cache
+	context   *Context
+	memoryMap *MemoryMap
+	isServed  bool // Whether the current request was served without the cache
+	isFaulted bool // Whether the current request faulted, so was unsuccessful
+}
+
+func (c *mappedCache) IsBusy() bool {
+	if c.isServed {
+		return false
+	}
+	return c.Cache.IsBusy()
+}
+
+func (c *mappedCache) IsFree() bool {
+	if c.isServed {
+		return false
+	}
+	return c.Cache.IsFree()
+}
+
+func (c *mappedCache) Free() {
+	if c.isServed {
+		c.isServed = false
+		c.isFaulted = false
+		return
+	}
+	c.Cache.Free()
+}
+
+func (c *mappedCache) IsSuccessful() bool {
+	if c.isServed {
+		return !c.isFaulted
+	}
+	return c.Cache.IsSuccessful()
+}
+
+func (c *mappedCache) Read(address uint64) {
+	if !c.serve(flamego.CacheRead, address, c.Bus().Size()) {
+		c.Cache.Read(address)
+	}
+}
+
+func (c *mappedCache) Write(address uint64) {
+	if !c.serve(flamego.CacheWrite, address, c.Bus().Size()) {
+		c.Cache.Write(address)
+	}
+}
+
+func (c *mappedCache) ReadBurst(address uint64, length int) {
+	if !c.serve(flamego.CacheRead, address, length) {
+		c.Cache.ReadBurst(address, length)
+	}
+}
+
+func (c *mappedCache) WriteBurst(address uint64, length int) {
+	if !c.serve(flamego.CacheWrite, address, length) {
+		c.Cache.WriteBurst(address, length)
+	}
+}
+
+func (c *mappedCache) Clear(address uint64) {
+	if !c.serve(flamego.CacheClear, address, c.Bus().Size()) {
+		c.Cache.Clear(address)
+	}
+}
+
+func (c *mappedCache) Flush(address uint64) {
+	if !c.serve(flamego.CacheFlush, address, c.Bus().Size()) {
+		c.Cache.Flush(address)
+	}
+}
+
+// serve returns true if the request faults, or is served by a device, and false if the request is for the cache.
+func (c *mappedCache) serve(operation flamego.CacheOperation, address uint64, length int) bool {
+	region, ok := c.memoryMap.Region(address, length)
+	switch {
+	case ok && region.Type == RegionRAM:
+		return false
+	case ok && region.Type == RegionROM && operation != flamego.CacheWrite:
+		return false
+	case !ok && (operation == flamego.CacheClear || operation == flamego.CacheFlush):
+		// Nothing is cached outside RAM and ROM
+	case ok && region.Type == RegionMMIO && address%flamego.DataSize == 0 && length == flamego.DataSize:
+		bus := c.Bus()
+		offset := address - region.Start
+		switch operation {
+		case flamego.CacheRead:
+			var buffer [flamego.DataSize]byte
+			binary.BigEndian.PutUint64(buffer[:], region.Device.ReadMMIO(offset))
+			for i, b := range buffer {
+				bus.Write(i, b)
+				bus.SetDirty(i, false)
+			}
+		case flamego.CacheWrite:
+			var buffer [flamego.DataSize]byte
+			for i := range buffer {
+				buffer[i] = bus.Read(i)
+				bus.SetDirty(i, false)
+			}
+			value := binary.BigEndian.Uint64(buffer[:])
+			write := func() {
+				region.Device.WriteMMIO(offset, value)
+			}
+			if p, ok := c.context.core.Processor().(*Processor); ok {
+				// Cores clocked in parallel share the device, so the write takes effect at the end of the cycle
+				p.schedule(flamego.ContextIdentifier(c.context), write)
+			} else {
+				write()
+			}
+		}
+	default:
+		c.context.Error(flamego.InterruptMemoryAccessError)
+		c.isFaulted = true
+		if operation == flamego.CacheRead {
+			bus := c.Bus()
+			for i := 0; i < length && i < bus.Size(); i++ {
+				bus.Write(i, 0)
+				bus.SetDirty(i, false)
+			}
+		}
+	}
+	c.isServed = true
+	return true
+}
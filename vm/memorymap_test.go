@@ -0,0 +1,225 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMap_Region(t *testing.T) {
+	m := vm.NewMemoryMap(MemorySize)
+	m.Add(vm.Region{
+		Type:  vm.RegionROM,
+		Start: 0x100,
+		Size:  0x100,
+	})
+
+	r, ok := m.Region(0, flamego.DataSize)
+	assert.True(t, ok)
+	assert.Equal(t, vm.RegionRAM, r.Type)
+
+	// Later regions are mapped over earlier regions
+	r, ok = m.Region(0x100, flamego.DataSize)
+	assert.True(t, ok)
+	assert.Equal(t, vm.RegionROM, r.Type)
+
+	// Bytes span two regions
+	_, ok = m.Region(0xf8, 2*flamego.DataSize)
+	assert.False(t, ok)
+
+	// Address is outside every region
+	_, ok = m.Region(MemorySize, flamego.DataSize)
+	assert.False(t, ok)
+	_, ok = m.Region(MemorySize-4, flamego.DataSize)
+	assert.False(t, ok)
+}
+
+func TestMemoryMap_Fault(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	cache := vm.NewCache(CacheSize, LineWidth, BusSize, OffsetBits, memory)
+	x := vm.NewContext(0, nil, cache, cache)
+	m := vm.NewMemoryMap(MemorySize)
+	m.Add(vm.Region{
+		Type: vm.RegionROM,
+		Size: 0x100,
+	})
+	x.SetMemoryMap(m)
+	d := x.DataCache()
+
+	for name, request := range map[string]func(){
+		"ROM Write":   func() { d.Write(0x80) },
+		"Unmapped":    func() { d.Read(MemorySize) },
+		"Split Burst": func() { d.ReadBurst(0xfc, 2*BusSize) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			request()
+			// Request completes unsuccessfully without the cache, raising an error
+			assert.False(t, d.IsBusy())
+			assert.False(t, d.IsFree())
+			assert.False(t, d.IsSuccessful())
+			assert.False(t, cache.IsBusy())
+			assert.Equal(t, flamego.InterruptMemoryAccessError, x.NextInterrupt())
+			d.Free()
+			assert.True(t, d.IsFree())
+		})
+	}
+
+	// ROM is read through the cache
+	d.Read(0x80)
+	assert.True(t, cache.IsBusy())
+	cache.Clock(0)
+	d.Free()
+}
+
+func TestMemoryMap_MMIO(t *testing.T) {
+	m := vm.NewMachine()
+	timer := vm.NewTimer(m.Memory, flamego.DeviceControlBlockAddress)
+	m.Processor.AddDevice(timer)
+	m.MemoryMap.Add(vm.Region{
+		Type:   vm.RegionMMIO,
+		Start:  flamego.SizeMemory,
+		Size:   vm.TimerWindowSize,
+		Device: timer,
+	})
+	m.Memory.Set(0, assemble(t, strings.NewReader(`
+loadc 0x100000 r16
+loadc 5000 r17
+store r16 8 r17
+load r16 8 r18
+load r16 0 r19
+halt
+`)))
+	m.Processor.Signal(flamego.InterruptSourceHost, 0)
+	for !m.Processor.HasHalted() {
+		m.Clock()
+	}
+	// Writing the period arms the timer
+	assert.True(t, timer.IsArmed())
+	x := m.Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(5000), x.ReadRegister(flamego.R18))
+	remaining := x.ReadRegister(flamego.R19)
+	assert.NotZero(t, remaining)
+	assert.LessOrEqual(t, remaining, uint64(5000))
+}
+
+func TestMemoryMap_MMIO_Parallel(t *testing.T) {
+	// Contexts on every core repeatedly write and read the registers of the same timer
+	program := `
+loadc #Descriptor r18
+loadc #Child r19
+store r18 24 r19
+loadc #ChildEnd r19
+store r18 32 r19
+loadc 8 r17
+loadc 8 r20
+loadc 64 r21
+#Spawn
+spawn r17 r18 r19
+add r17 r20 r17
+subtract r17 r21 r22
+jlz r22 #Spawn
+loadc 7 r21
+#Receive
+receive r22
+add r23 r22 r23
+subtract r21 r1 r21
+jez r21 #Done
+jez r0 #Receive
+#Done
+halt
+
+#Child
+loadc 0x100000 r16
+loadc 100 r19
+#Access
+store r16 8 r2
+load r16 8 r17
+load r16 0 r18
+add r17 r20 r20
+add r18 r20 r20
+subtract r19 r1 r19
+jnz r19 #Access
+send r0 r20 r21
+exit
+#ChildEnd
+
+align 0x100
+#Descriptor
+allocate 10
+`
+	run := func(parallel bool) (*vm.Machine, *vm.Timer) {
+		m := vm.NewMachineWithConfig(vm.Config{Parallel: parallel})
+		timer := vm.NewTimer(m.Memory, flamego.DeviceControlBlockAddress)
+		m.Processor.AddDevice(timer)
+		m.MemoryMap.Add(vm.Region{
+			Type:   vm.RegionMMIO,
+			Start:  flamego.SizeMemory,
+			Size:   vm.TimerWindowSize,
+			Device: timer,
+		})
+		m.Memory.Set(0, assemble(t, strings.NewReader(program)))
+		m.Processor.Signal(flamego.InterruptSourceHost, 0)
+		for !m.Processor.HasHalted() {
+			if m.Tick > 10000000 {
+				t.Fatal("Processor never halted")
+			}
+			m.Clock()
+		}
+		return m, timer
+	}
+	serial, serialTimer := run(false)
+	parallel, parallelTimer := run(true)
+	assert.True(t, parallelTimer.IsArmed())
+	// Writes take effect at the end of the cycle in core order, so both modes read the same registers
+	assert.Equal(t, serialTimer.ReadMMIO(vm.TimerRegisterPeriod), parallelTimer.ReadMMIO(vm.TimerRegisterPeriod))
+	assert.Equal(t, serial.Processor.Core(0).Context(0).ReadRegister(flamego.R23), parallel.Processor.Core(0).Context(0).ReadRegister(flamego.R23))
+	assert.Equal(t, serial.Tick, parallel.Tick)
+}
+
+func TestMemoryMap_Fault_Interrupted(t *testing.T) {
+	for name, program := range map[string]string{
+		// Store to ROM
+		"Write": `
+loadc 0x40 r16
+store r16 0 r16
+loadc 1 r17
+halt
+`,
+		// Fetch from unmapped memory
+		"Fetch": `
+loadc 0x800 rSP
+loadc 0x900 rSL
+loadc 0x100000 r16
+call r16
+loadc 1 r17
+halt
+`,
+	} {
+		for core, config := range map[string]vm.Config{
+			"Barrel":    {},
+			"Pipelined": {Core: vm.CorePipelined},
+		} {
+			t.Run(name+" "+core, func(t *testing.T) {
+				m := vm.NewMachineWithConfig(config)
+				m.MemoryMap.Add(vm.Region{
+					Type: vm.RegionROM,
+					Size: 0x100,
+				})
+				m.Memory.Set(0, assemble(t, strings.NewReader(program)))
+				m.Processor.Signal(flamego.InterruptSourceHost, 0)
+				for !m.Processor.HasHalted() {
+					if m.Tick > 1000000 {
+						t.Fatal("Processor never halted")
+					}
+					m.Clock()
+				}
+				// A fault while interrupted halts the processor, without retiring the faulting instruction
+				x := m.Processor.Core(0).Context(0)
+				assert.Equal(t, uint64(0), x.ReadRegister(flamego.R17))
+				assert.Equal(t, make([]byte, flamego.DataSize), m.Memory.Get(0x40, flamego.DataSize))
+			})
+		}
+	}
+}
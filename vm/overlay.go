@@ -0,0 +1,123 @@
+package vm
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// Unit: Bytes
+const OverlayBlockSize = 512
+
+// Each record of an overlay file is the 64bit number of a block followed by its bytes.
+const overlayRecordSize = 8 + OverlayBlockSize
+
+// NewOverlay returns a copy-on-write overlay of the base image, reading the blocks already written to the overlay file.
+func NewOverlay(base io.ReaderAt, file *os.File) (*Overlay, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	o := &Overlay{
+		base:   base,
+		file:   file,
+		size:   info.Size() - info.Size()%overlayRecordSize,
+		blocks: make(map[uint64]int64),
+	}
+	var number [8]byte
+	for position := int64(0); position < o.size; position += overlayRecordSize {
+		if _, err := file.ReadAt(number[:], position); err != nil {
+			return nil, err
+		}
+		o.blocks[binary.BigEndian.Uint64(number[:])] = position
+	}
+	return o, nil
+}
+
+// Overlay reads blocks from its file once they have been written, and from the base image otherwise, so writes never reach the base image.
+type Overlay struct {
+	base   io.ReaderAt
+	file   *os.File
+	size   int64            // Bytes of complete records in the file
+	blocks map[uint64]int64 // Position of the record of each written block
+}
+
+// Blocks returns the number of blocks written to the overlay.
+func (o *Overlay) Blocks() int {
+	return len(o.blocks)
+}
+
+func (o *Overlay) ReadAt(p []byte, offset int64) (int, error) {
+	count := 0
+	for count < len(p) {
+		block, inner, length := o.span(offset+int64(count), len(p)-count)
+		var n int
+		var err error
+		if position, ok := o.blocks[block]; ok {
+			n, err = o.file.ReadAt(p[count:count+length], position+8+inner)
+		} else {
+			n, err = o.base.ReadAt(p[count:count+length], offset+int64(count))
+		}
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (o *Overlay) WriteAt(p []byte, offset int64) (int, error) {
+	count := 0
+	for count < len(p) {
+		block, inner, length := o.span(offset+int64(count), len(p)-count)
+		position, ok := o.blocks[block]
+		if !ok {
+			// Copy the block from the base image before it is first written
+			var err error
+			if position, err = o.copy(block); err != nil {
+				return count, err
+			}
+		}
+		n, err := o.file.WriteAt(p[count:count+length], position+8+inner)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (o *Overlay) Close() error {
+	if c, ok := o.base.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	return o.file.Close()
+}
+
+// span returns the block holding the offset, the offset within the block, and the bytes of the length within the block.
+func (o *Overlay) span(offset int64, length int) (uint64, int64, int) {
+	block := uint64(offset / OverlayBlockSize)
+	inner := offset % OverlayBlockSize
+	if remaining := int(OverlayBlockSize - inner); length > remaining {
+		length = remaining
+	}
+	return block, inner, length
+}
+
+// copy appends a record of the block as read from the base image, beyond the end of which reads as zero.
+func (o *Overlay) copy(block uint64) (int64, error) {
+	record := make([]byte, overlayRecordSize)
+	binary.BigEndian.PutUint64(record, block)
+	if _, err := o.base.ReadAt(record[8:], int64(block*OverlayBlockSize)); err != nil && err != io.EOF {
+		return 0, err
+	}
+	position := o.size
+	if _, err := o.file.WriteAt(record, position); err != nil {
+		return 0, err
+	}
+	o.size += overlayRecordSize
+	o.blocks[block] = position
+	return position, nil
+}
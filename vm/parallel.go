@@ -0,0 +1,175 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"runtime"
+	"sync/atomic"
+)
+
+// worker clocks a single core on its own goroutine.
+type worker struct {
+	core  flamego.Core
+	runs  chan span
+	done  chan struct{} // Closed when the goroutine returns
+	panic interface{}
+}
+
+// span is the cycles clocked by the workers between two dispatches.
+type span struct {
+	start, end int
+}
+
+func (w *worker) run(p *Processor) {
+	defer close(w.done)
+	for r := range w.runs {
+		for cycle := r.start; ; cycle++ {
+			w.clock(cycle)
+			if !p.arrive(cycle, r.end, w.panic != nil) {
+				break
+			}
+		}
+		p.barrier.Done()
+	}
+}
+
+func (w *worker) clock(cycle int) {
+	defer func() {
+		// Recover so the panic can be raised again on the clocking goroutine
+		w.panic = recover()
+	}()
+	w.core.Clock(cycle)
+}
+
+// IsParallel returns true if the cores are clocked concurrently.
+func (p *Processor) IsParallel() bool {
+	return p.parallel
+}
+
+// SetParallel clocks each core on its own goroutine, waiting for every core at the end of each cycle.
+// Results are identical to clocking the cores sequentially as each core only has access to its own caches and contexts,
+// and the effects of contexts on shared state are applied in core order at the end of the cycle.
+// Run dispatches the cycles up to the next clock of the L2 caches at once, and the cores wait for each other at the end of each cycle without returning to the clocking goroutine.
+// The goroutines are stopped when parallel mode is disabled, or the processor halts.
+func (p *Processor) SetParallel(parallel bool) {
+	if parallel == p.parallel {
+		return
+	}
+	p.parallel = parallel
+	if !parallel {
+		p.stopWorkers()
+	}
+}
+
+// stopWorkers stops the goroutines, waiting for each to return.
+func (p *Processor) stopWorkers() {
+	for _, w := range p.workers {
+		close(w.runs)
+	}
+	for _, w := range p.workers {
+		<-w.done
+	}
+	p.workers = nil
+}
+
+// clockParallel clocks the cores from the start cycle until the end cycle, or until the processor halts, returning the number of cycles clocked.
+func (p *Processor) clockParallel(start, end int) int {
+	if len(p.workers) != len(p.cores) {
+		// Start a worker for each core added since the last cycle
+		for _, c := range p.cores[len(p.workers):] {
+			w := &worker{
+				core: c,
+				runs: make(chan span),
+				done: make(chan struct{}),
+			}
+			go w.run(p)
+			p.workers = append(p.workers, w)
+		}
+	}
+	if len(p.workers) == 0 {
+		// Without cores, there are no workers to settle the cycle
+		p.clocking = false
+		p.settle(start)
+		return 1
+	}
+	p.clocked = 0
+	p.stopped = 0
+	p.barrier.Add(len(p.workers))
+	for _, w := range p.workers {
+		w.runs <- span{start, end}
+	}
+	p.barrier.Wait()
+	p.clocking = false
+	for _, w := range p.workers {
+		if v := w.panic; v != nil {
+			p.stopWorkers()
+			panic(v)
+		}
+	}
+	if v := p.fault; v != nil {
+		p.stopWorkers()
+		panic(v)
+	}
+	if p.HasHalted() {
+		p.stopWorkers()
+	}
+	return p.clocked
+}
+
+// arrive waits for every core to finish the cycle, returning true if the cores continue to the next cycle of the run.
+// The last core to arrive settles the cycle while the others wait, so shared state is only changed between cycles.
+func (p *Processor) arrive(cycle, end int, failed bool) bool {
+	generation := atomic.LoadUint32(&p.generation)
+	if failed {
+		atomic.StoreInt32(&p.stopped, 1)
+	}
+	if int(atomic.AddInt32(&p.arrived, 1)) == len(p.workers) {
+		atomic.StoreInt32(&p.arrived, 0)
+		if atomic.LoadInt32(&p.stopped) == 0 {
+			p.clocking = false
+			p.settleSafely(cycle)
+			p.clocking = true
+			p.clocked++
+			if cycle+1 == end || p.HasHalted() || p.fault != nil {
+				atomic.StoreInt32(&p.stopped, 1)
+			} else {
+				p.cycle = uint64(cycle + 1)
+			}
+		}
+		// Release the waiting cores
+		atomic.AddUint32(&p.generation, 1)
+	} else {
+		for atomic.LoadUint32(&p.generation) == generation {
+			runtime.Gosched()
+		}
+	}
+	return atomic.LoadInt32(&p.stopped) == 0
+}
+
+// settleSafely settles the cycle, recovering so the panic can be raised again on the clocking goroutine.
+func (p *Processor) settleSafely(cycle int) {
+	defer func() {
+		p.fault = recover()
+	}()
+	p.settle(cycle)
+}
+
+// schedule applies the effect of the source on shared state at the end of the cycle if the source is a context of a core being clocked,
+// otherwise the effect is applied immediately.
+func (p *Processor) schedule(source int, effect func()) {
+	if p.clocking && source >= 0 && source < len(p.cores)*flamego.ContextCount {
+		core := source / flamego.ContextCount
+		p.deferred[core] = append(p.deferred[core], effect)
+		return
+	}
+	effect()
+}
+
+// applyDeferred applies the effects of each core in core order, so results do not depend on the order in which the cores were clocked.
+func (p *Processor) applyDeferred() {
+	for i, effects := range p.deferred {
+		for _, e := range effects {
+			e()
+		}
+		p.deferred[i] = effects[:0]
+	}
+}
@@ -0,0 +1,136 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/assembler"
+	"aletheiaware.com/flamego/vm"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const parallelProgram = `
+loadc #Descriptor r18
+loadc #Child r19
+store r18 24 r19
+loadc #ChildEnd r19
+store r18 32 r19
+loadc 8 r17
+loadc 8 r20
+loadc 64 r21
+#Spawn
+spawn r17 r18 r19
+add r17 r20 r17
+subtract r17 r21 r22
+jlz r22 #Spawn
+loadc 7 r21
+#Receive
+receive r22
+add r23 r22 r23
+subtract r21 r1 r21
+jez r21 #Done
+jez r0 #Receive
+#Done
+halt
+
+#Child
+loadc 0 r16
+multiply r2 r2 r17
+send r16 r17 r18
+exit
+#ChildEnd
+
+align 0x100
+#Descriptor
+allocate 10
+`
+
+func BenchmarkProcessor_Parallel(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	a := assembler.NewAssembler()
+	if _, err := a.ReadFrom(strings.NewReader(parallelProgram)); err != nil {
+		b.Fatal(err)
+	}
+	var program bytes.Buffer
+	if _, err := a.WriteTo(&program); err != nil {
+		b.Fatal(err)
+	}
+	for name, parallel := range map[string]bool{
+		"Serial":   false,
+		"Parallel": true,
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				m := vm.NewMachineWithConfig(vm.Config{Parallel: parallel})
+				m.Memory.Set(0, program.Bytes())
+				m.Processor.Signal(flamego.InterruptSourceHost, 0)
+				b.StartTimer()
+				for !m.Processor.HasHalted() {
+					m.Clock()
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_Parallel(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	run := func(parallel bool) *vm.Machine {
+		m := vm.NewMachineWithConfig(vm.Config{Parallel: parallel})
+		m.Memory.Set(0, assemble(t, strings.NewReader(parallelProgram)))
+		m.Processor.Signal(flamego.InterruptSourceHost, 0)
+		for !m.Processor.HasHalted() {
+			if m.Tick > 1000000 {
+				t.Fatal("Processor never halted")
+			}
+			m.Clock()
+		}
+		return m
+	}
+
+	m1 := run(false)
+	m2 := run(true)
+	assert.True(t, m2.Processor.IsParallel())
+
+	// Workers were stopped when the processor halted
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+
+	assert.Equal(t, m1.Tick, m2.Tick)
+	assert.Equal(t, m1.Memory.Get(0, flamego.SizeMemory), m2.Memory.Get(0, flamego.SizeMemory))
+	for i := 0; i < flamego.CoreCount; i++ {
+		for j := 0; j < flamego.ContextCount; j++ {
+			x1 := m1.Processor.Core(i).Context(j).(*vm.Context)
+			x2 := m2.Processor.Core(i).Context(j).(*vm.Context)
+			for r := flamego.R0; r <= flamego.R31; r++ {
+				assert.Equal(t, x1.ReadRegister(r), x2.ReadRegister(r))
+			}
+			assert.Equal(t, x1.SleepCycles(), x2.SleepCycles())
+			assert.Equal(t, x1.RetiredInstructions(), x2.RetiredInstructions())
+		}
+	}
+	// Sum of the squares of the core identifiers of each child
+	assert.Equal(t, uint64(140), m1.Processor.Core(0).Context(0).ReadRegister(flamego.R23))
+	for i := 1; i < flamego.CoreCount; i++ {
+		assert.True(t, m1.Processor.Core(i).Context(0).IsAsleep())
+	}
+}
+
+func TestProcessor_Parallel_Run(t *testing.T) {
+	m := vm.NewMachineWithConfig(vm.Config{Parallel: true})
+	m.Memory.Set(0, assemble(t, strings.NewReader(parallelProgram)))
+	m.Processor.Signal(flamego.InterruptSourceHost, 0)
+	// Cores run until the next clock of the L2 caches
+	m.Clock()
+	assert.Equal(t, 10, m.Tick)
+	assert.Equal(t, 5, m.Processor.Run(15))
+	m.Processor.SetParallel(false)
+	assert.Equal(t, 1, m.Processor.Run(20))
+}
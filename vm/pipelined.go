@@ -0,0 +1,509 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/isa"
+	"encoding/binary"
+)
+
+// Unit: Entries
+const PredictorSize = 256
+
+func NewPipelinedCore(id int, processor flamego.Processor, cache flamego.Cache) *PipelinedCore {
+	c := &PipelinedCore{
+		id:        id,
+		processor: processor,
+		cache:     cache,
+	}
+	for i := range c.counters {
+		c.counters[i] = 1 // Weakly not taken
+	}
+	return c
+}
+
+// PipelinedCore runs a single context through a classic 5-stage in-order pipeline;
+//
+//   - Fetch: reads the next instruction from the L1 Instruction Cache
+//   - Decode: decodes the instruction, and predicts the direction of jumps
+//   - Execute: loads operands, and executes the operation
+//   - Memory: formats data from the L1 Data Cache, stores results, and retires the instruction
+//   - Writeback: the instruction leaves the pipeline
+//
+// Results are stored in the memory stage, and read by the next instruction in its execute stage during the same cycle,
+// which models forwarding from the memory and writeback stages.
+// An instruction using data loaded by the previous instruction stalls for one cycle,
+// an instruction waiting for a cache stalls in the memory stage, and a mispredicted jump flushes the fetch and decode stages.
+// Instructions only execute once every older instruction has completed, so errors and effects on the processor are never speculative.
+type PipelinedCore struct {
+	id         int
+	processor  flamego.Processor
+	cache      flamego.Cache
+	context    *Context
+	decodes    isa.DecodeCache
+	counters   [PredictorSize]uint8 // 2-bit saturating counters indexed by Program Counter
+	statistics PipelineStatistics
+
+	fetching bool   // Whether instructions are fetched from fetchPC
+	fetchPC  uint64 // Program Counter of the next instruction to fetch
+	bubble   bool   // Whether the fetch stage is idle this cycle while redirected
+
+	// Instructions waiting to enter each stage
+	fetched   *slot // Decode
+	decoded   *slot // Execute
+	executed  *slot // Memory
+	completed *slot // Writeback
+
+	writeback *slot // Instruction leaving the pipeline this cycle
+}
+
+// PipelineStatistics counts events in a pipelined core.
+type PipelineStatistics struct {
+	Cycles         uint64 // Cycles clocked, or skipped while quiescent
+	Forwards       uint64 // Instructions reading results stored in the previous two cycles
+	LoadUseStalls  uint64 // Cycles an instruction waited for data loaded by the previous instruction
+	MemoryStalls   uint64 // Cycles an instruction waited in the memory stage
+	FetchStalls    uint64 // Cycles the fetch and decode stages waited for the instruction cache
+	Flushes        uint64 // Instructions discarded from the fetch and decode stages
+	Predictions    uint64 // Jumps predicted
+	Mispredictions uint64 // Jumps predicted incorrectly
+}
+
+// slot holds an instruction in flight.
+type slot struct {
+	pc           uint64 // Program Counter of the instruction
+	predicted    uint64 // Program Counter predicted to follow the instruction
+	aligned      bool
+	fault        bool                   // Instruction could not be fetched
+	err          flamego.InterruptValue // Error raised if a fault executes
+	opcode       uint32
+	instruction  flamego.Instruction
+	state        flamego.InstructionState
+	sources      isa.Registers
+	destinations isa.Registers
+	serializing  bool // Fetching stops until the instruction completes as the next Program Counter is unknown
+	retrying     bool
+	stored       bool   // Instruction is waiting to retire
+	e, f         uint64 // Results of the execute stage
+}
+
+func (c *PipelinedCore) Id() int {
+	return c.id
+}
+
+func (c *PipelinedCore) Processor() flamego.Processor {
+	return c.processor
+}
+
+// Context returns the only context of the core, or nil for any other index.
+func (c *PipelinedCore) Context(index int) flamego.Context {
+	if index != 0 || c.context == nil {
+		return nil
+	}
+	return c.context
+}
+
+func (c *PipelinedCore) AddContext(x flamego.Context) {
+	if c.context != nil {
+		panic("Pipelined Core already has a context")
+	}
+	c.context = x.(*Context)
+}
+
+func (c *PipelinedCore) Cache() flamego.Cache {
+	return c.cache
+}
+
+// DecodeCache returns the instructions decoded by the core.
+func (c *PipelinedCore) DecodeCache() *isa.DecodeCache {
+	return &c.decodes
+}
+
+func (c *PipelinedCore) Statistics() PipelineStatistics {
+	return c.statistics
+}
+
+// Clock the core, the L2 Cache is clocked by the processor.
+func (c *PipelinedCore) Clock(cycle int) {
+	x := c.context
+
+	// Clock L1 Caches
+	x.iCache.Clock(cycle)
+	x.dCache.Clock(cycle)
+
+	c.statistics.Cycles++
+
+	// Run the stages in reverse so each stage sees the effects of the older instructions ahead of it.
+	c.writeback = c.completed
+	c.completed = nil
+	c.memory()
+	c.execute()
+	c.decode()
+	c.fetch()
+}
+
+// enter makes the slot the instruction of the context, returning the Program Counter to be restored by leave.
+func (c *PipelinedCore) enter(s *slot) uint64 {
+	x := c.context
+	pc := x.registers[flamego.RProgramCounter]
+	x.registers[flamego.RProgramCounter] = s.pc
+	x.opcode = s.opcode
+	x.instruction = s.instruction
+	x.state = s.state
+	return pc
+}
+
+// leave saves the state of the instruction, returning the Program Counter it updated.
+func (c *PipelinedCore) leave(s *slot, pc uint64) uint64 {
+	x := c.context
+	s.state = x.state
+	next := x.registers[flamego.RProgramCounter]
+	x.registers[flamego.RProgramCounter] = pc
+	return next
+}
+
+func (c *PipelinedCore) memory() {
+	s := c.executed
+	if s == nil {
+		return
+	}
+	x := c.context
+	i := s.instruction
+	pc := c.enter(s)
+	if s.retrying {
+		// Repeat the whole instruction as in the barrel core, formatting in the next cycle once the caches have been clocked
+		a, b, cc, d := i.Load(x)
+		s.e, s.f = i.Execute(x, a, b, cc, d)
+		s.retrying = false
+		c.leave(s, pc)
+		c.statistics.MemoryStalls++
+		return
+	}
+	if !s.stored {
+		g, h := i.Format(x, s.e, s.f)
+		i.Store(x, g, h)
+		if _, ok := i.(*isa.Spawn); ok && x.state.Index == flamego.SpawnDescriptorLength {
+			// Contexts are spawned at the end of the cycle, so the result is retired in the next cycle
+			s.stored = true
+			c.leave(s, pc)
+			c.statistics.MemoryStalls++
+			return
+		}
+	}
+	s.stored = false
+	retired := i.Retire(x)
+	next := c.leave(s, pc)
+	if !retired && x.nextInterrupt >= 0 {
+		// Instruction raised an error, so is abandoned for the interrupt, which returns to the instruction
+		x.status = "abandoned instruction"
+		c.executed = nil
+		c.flush()
+		c.fetching = false
+		return
+	}
+	if !retired {
+		s.retrying = true
+		x.status = "retrying instruction"
+		c.statistics.MemoryStalls++
+		return
+	}
+	x.registers[flamego.RProgramCounter] = next
+	x.retired++
+	x.status = "retired instruction"
+	c.executed = nil
+	c.completed = s
+
+	switch {
+	case x.nextInterrupt >= 0:
+		// Discard younger instructions and take the interrupt
+		c.flush()
+		c.fetching = false
+	case s.serializing, next != s.predicted:
+		c.redirect(next)
+	}
+}
+
+func (c *PipelinedCore) execute() {
+	s := c.decoded
+	if s == nil || c.executed != nil {
+		// Nothing decoded, or the memory stage is occupied
+		return
+	}
+	x := c.context
+	if s.fault || s.instruction == nil {
+		// Errors are raised once the instruction is no longer speculative
+		c.decoded = nil
+		c.flush()
+		c.fetching = false
+		if s.fault {
+			x.Error(s.err)
+		} else {
+			// Unrecognized Opcode
+			c.decodes.Decode(s.opcode)
+		}
+		return
+	}
+	if p := c.completed; p != nil && isa.ReadsMemory(p.instruction) && p.destinations.Contains(s.sources) {
+		// Data loaded by the previous instruction is not available until the end of its memory stage
+		c.statistics.LoadUseStalls++
+		return
+	}
+	if p := c.completed; p != nil && p.destinations.Contains(s.sources) {
+		c.statistics.Forwards++
+	} else if p := c.writeback; p != nil && p.destinations.Contains(s.sources) {
+		c.statistics.Forwards++
+	}
+
+	pc := c.enter(s)
+	a, b, cc, d := s.instruction.Load(x)
+	s.e, s.f = s.instruction.Execute(x, a, b, cc, d)
+	c.leave(s, pc)
+	c.decoded = nil
+	x.status = "executed operation"
+
+	if x.isAsleep {
+		// Sleep, Exit, and Receive without a message don't complete, the context fetches again when woken
+		c.flush()
+		c.fetching = false
+		return
+	}
+	c.executed = s
+	if j, ok := s.instruction.(*isa.Jump); ok {
+		// Execute returns the next Program Counter
+		c.train(s.pc, j, s.e != s.pc+flamego.InstructionSize)
+		if s.e != s.predicted {
+			c.statistics.Mispredictions++
+			s.predicted = s.e
+			c.redirect(s.e)
+		}
+	}
+	if x.nextInterrupt >= 0 {
+		c.flush()
+		c.fetching = false
+	}
+}
+
+func (c *PipelinedCore) decode() {
+	s := c.fetched
+	if s == nil || c.decoded != nil {
+		// Nothing fetched, or the execute stage is occupied
+		return
+	}
+	if !s.fault {
+		is := c.context.iCache
+		if is.IsBusy() {
+			c.statistics.FetchStalls++
+			return
+		}
+		if !is.IsSuccessful() {
+			// Cache Miss, fetch again
+			is.Free()
+			c.fetched = nil
+			c.fetchPC = s.pc
+			c.statistics.FetchStalls++
+			return
+		}
+		bus := is.Bus()
+		offset := 0
+		if !s.aligned {
+			offset += flamego.InstructionSize
+		}
+		s.opcode = binary.BigEndian.Uint32([]byte{
+			bus.Read(offset + 0),
+			bus.Read(offset + 1),
+			bus.Read(offset + 2),
+			bus.Read(offset + 3),
+		})
+		is.Free()
+		s.instruction = c.decodeOpcode(s.opcode)
+	}
+	c.fetched = nil
+	c.decoded = s
+	c.predict(s)
+}
+
+// decodeOpcode returns nil if the opcode is unrecognized, as it may have been fetched speculatively.
+func (c *PipelinedCore) decodeOpcode(opcode uint32) (instruction flamego.Instruction) {
+	defer func() {
+		if recover() != nil {
+			instruction = nil
+		}
+	}()
+	return c.decodes.Decode(opcode)
+}
+
+// predict sets the Program Counter predicted to follow the decoded instruction, redirecting fetch to the target of jumps predicted taken.
+func (c *PipelinedCore) predict(s *slot) {
+	s.predicted = s.pc + flamego.InstructionSize
+	s.sources, s.destinations = isa.Operands(s.instruction)
+	switch i := s.instruction.(type) {
+	case *isa.Jump:
+		c.statistics.Predictions++
+		if c.isTaken(s.pc, i) {
+			if i.Direction == isa.JumpBackward {
+				s.predicted = s.pc - uint64(i.Offset)
+			} else {
+				s.predicted = s.pc + uint64(i.Offset)
+			}
+			c.fetchPC = s.predicted
+			// The target is fetched in the next cycle
+			c.bubble = true
+		}
+	case nil, *isa.Call, *isa.Return, *isa.Interrupt, *isa.Uninterrupt, *isa.Halt, *isa.Sleep, *isa.Exit, *isa.Clear:
+		// Control flow changes, or the instruction cache is required
+		s.serializing = true
+		c.fetching = false
+	}
+}
+
+func (c *PipelinedCore) index(pc uint64) int {
+	return int(pc/flamego.InstructionSize) % PredictorSize
+}
+
+// isTaken predicts whether the jump is taken; jumps conditional on r0 are always, or never, taken.
+func (c *PipelinedCore) isTaken(pc uint64, j *isa.Jump) bool {
+	if j.ConditionRegister == flamego.R0 {
+		return j.ConditionCode == isa.JumpEZ || j.ConditionCode == isa.JumpLE
+	}
+	return c.counters[c.index(pc)] >= 2
+}
+
+func (c *PipelinedCore) train(pc uint64, j *isa.Jump, taken bool) {
+	if j.ConditionRegister == flamego.R0 {
+		return
+	}
+	counter := &c.counters[c.index(pc)]
+	if taken && *counter < 3 {
+		*counter++
+	} else if !taken && *counter > 0 {
+		*counter--
+	}
+}
+
+func (c *PipelinedCore) fetch() {
+	x := c.context
+	if c.bubble {
+		c.bubble = false
+		return
+	}
+	if c.fetched != nil {
+		// Decode stage is occupied
+		return
+	}
+	if x.nextInterrupt >= 0 || x.isSignalPending() {
+		// Interrupts are taken between instructions, once those in flight have completed
+		c.fetching = false
+		if c.decoded == nil && c.executed == nil {
+			c.interrupt()
+		}
+		return
+	}
+	if x.isAsleep {
+		x.sleepCycles++
+		return
+	}
+	if !c.fetching {
+		if c.decoded != nil || c.executed != nil {
+			// Waiting for a serializing instruction to complete
+			return
+		}
+		// Context has been woken or spawned
+		c.fetchPC = x.registers[flamego.RProgramCounter]
+		c.fetching = true
+	}
+
+	pc := c.fetchPC
+	address := pc
+	if !x.isInterrupted {
+		address += x.registers[flamego.RProgramStart]
+		if address >= x.registers[flamego.RProgramLimit] {
+			c.fault(pc, flamego.InterruptProgramAccessError)
+			return
+		}
+	}
+	if address%flamego.InstructionSize != 0 {
+		c.fault(pc, flamego.InterruptProgramAccessError)
+		return
+	}
+	if !x.isExecutable(address) {
+		c.fault(pc, flamego.InterruptMemoryAccessError)
+		return
+	}
+	is := x.iCache
+	if is.IsBusy() || !is.IsFree() {
+		c.statistics.FetchStalls++
+		return
+	}
+	s := &slot{
+		pc:      pc,
+		aligned: address%flamego.DataSize == 0,
+	}
+	if !s.aligned {
+		// Always read in multiples of DataSize
+		address -= flamego.InstructionSize
+	}
+	is.Read(address)
+	x.status = "fetched instruction"
+	c.fetched = s
+	c.fetchPC = pc + flamego.InstructionSize
+}
+
+// fault passes an instruction which could not be fetched down the pipeline, raising an error if it executes.
+func (c *PipelinedCore) fault(pc uint64, err flamego.InterruptValue) {
+	c.fetched = &slot{
+		pc:    pc,
+		fault: true,
+		err:   err,
+	}
+	c.fetching = false
+}
+
+// interrupt starts the interrupt service routine in place of the next instruction.
+func (c *PipelinedCore) interrupt() {
+	x := c.context
+	if x.nextInterrupt < 0 {
+		x.takeSignal()
+	}
+	s := &slot{
+		pc:     x.registers[flamego.RProgramCounter],
+		opcode: isa.Encode(isa.NewInterrupt(x.nextInterrupt)),
+	}
+	x.nextInterrupt = -1
+	s.instruction = c.decodes.Decode(s.opcode)
+	c.decoded = s
+	c.predict(s)
+}
+
+// flush discards the instructions in the fetch and decode stages.
+func (c *PipelinedCore) flush() {
+	if s := c.fetched; s != nil {
+		if !s.fault {
+			// Discard the instruction being read
+			c.context.iCache.Free()
+		}
+		c.fetched = nil
+		c.statistics.Flushes++
+	}
+	if c.decoded != nil {
+		c.decoded = nil
+		c.statistics.Flushes++
+	}
+}
+
+// redirect flushes younger instructions and fetches from the given Program Counter in the next cycle.
+func (c *PipelinedCore) redirect(pc uint64) {
+	c.flush()
+	c.fetchPC = pc
+	c.fetching = true
+	c.bubble = true
+}
+
+func (c *PipelinedCore) IsQuiescent() bool {
+	return c.fetched == nil && c.decoded == nil && c.executed == nil && c.completed == nil && !c.bubble && isQuiescent(c.cache) && c.context.IsQuiescent()
+}
+
+// Skip accounts for cycles spent asleep without being clocked.
+func (c *PipelinedCore) Skip(cycles int) {
+	c.context.sleepCycles += cycles
+	c.statistics.Cycles += uint64(cycles)
+	c.writeback = nil
+}
@@ -0,0 +1,172 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/assembler"
+	"aletheiaware.com/flamego/vm"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assemble(t *testing.T, source io.Reader) []byte {
+	t.Helper()
+	a := assembler.NewAssembler()
+	_, err := a.ReadFrom(source)
+	assert.NoError(t, err)
+	var program bytes.Buffer
+	_, err = a.WriteTo(&program)
+	assert.NoError(t, err)
+	return program.Bytes()
+}
+
+func runMachine(t *testing.T, config vm.Config, source io.Reader) *vm.Machine {
+	t.Helper()
+	m := vm.NewMachineWithConfig(config)
+	m.Memory.Set(0, assemble(t, source))
+	m.Processor.Signal(flamego.InterruptSourceHost, 0)
+	for !m.Processor.HasHalted() {
+		if m.Tick > 10000000 {
+			t.Fatal("Processor never halted")
+		}
+		m.Clock()
+	}
+	return m
+}
+
+// Sample programs run by a single context
+var samples = []string{
+	"add",
+	"call",
+	"loop",
+	"mailbox",
+	"saverestore",
+	"storeandflush",
+}
+
+// Writing the Stack Pointer outside of an interrupt raises an error, the younger instructions are discarded
+const errorProgram = `
+loadc #Vectors rIVT
+loadc #Program rPS
+loadc #ProgramEnd rPL
+uninterrupt r0
+
+align 0x100
+#Vectors
+halt
+#RegisterAccessError
+copy rIV r19
+copy rIRA r20
+halt
+
+#Program
+loadc 7 r16
+loadc 0x10 rSP
+loadc 9 r18
+#ProgramEnd
+`
+
+// loadSamples returns the source of each sample program, by name.
+func loadSamples(t *testing.T, names ...string) map[string]string {
+	t.Helper()
+	programs := make(map[string]string)
+	for _, name := range names {
+		source, err := os.ReadFile(filepath.Join("..", "assembler", "samples", name+".fas"))
+		assert.NoError(t, err)
+		programs[name] = string(source)
+	}
+	return programs
+}
+
+// assertSameAsBarrel runs each program on the default machine, and on a machine with the config,
+// asserting that every context of the first core ends with the same registers and retired instructions.
+// Returns the default and configured machines of each program, by name.
+func assertSameAsBarrel(t *testing.T, config vm.Config, programs map[string]string) (map[string]*vm.Machine, map[string]*vm.Machine) {
+	t.Helper()
+	barrels := make(map[string]*vm.Machine)
+	machines := make(map[string]*vm.Machine)
+	for name, program := range programs {
+		barrel := runMachine(t, vm.Config{}, strings.NewReader(program))
+		m := runMachine(t, config, strings.NewReader(program))
+		for i := 0; i < flamego.ContextCount; i++ {
+			x1 := barrel.Processor.Core(0).Context(i)
+			x2 := m.Processor.Core(0).Context(i)
+			if x2 == nil {
+				// Pipelined cores have a single context
+				break
+			}
+			for r := flamego.R16; r <= flamego.R31; r++ {
+				assert.Equal(t, x1.ReadRegister(r), x2.ReadRegister(r), name+" "+r.String())
+			}
+			assert.Equal(t, x1.RetiredInstructions(), x2.RetiredInstructions(), name)
+		}
+		barrels[name] = barrel
+		machines[name] = m
+	}
+	return barrels, machines
+}
+
+func TestPipelinedCore_Samples(t *testing.T) {
+	barrels, machines := assertSameAsBarrel(t, vm.Config{Core: vm.CorePipelined}, loadSamples(t, samples...))
+	for name, m := range machines {
+		// A single context issues every cycle, instead of every 8 cycles
+		assert.Less(t, m.Tick, barrels[name].Tick, name)
+	}
+}
+
+func TestPipelinedCore_Spawn(t *testing.T) {
+	// Each core has a single context, so children are spawned on the first context of the other cores
+	m := runMachine(t, vm.Config{Core: vm.CorePipelined}, strings.NewReader(parallelProgram))
+	// Sum of the squares of the core identifiers of each child
+	assert.Equal(t, uint64(140), m.Processor.Core(0).Context(0).ReadRegister(flamego.R23))
+	for i := 1; i < flamego.CoreCount; i++ {
+		assert.True(t, m.Processor.Core(i).Context(0).IsAsleep())
+		assert.Nil(t, m.Processor.Core(i).Context(1))
+	}
+}
+
+func TestPipelinedCore_Hazards(t *testing.T) {
+	m := runMachine(t, vm.Config{Core: vm.CorePipelined}, strings.NewReader(`
+loadc #Data r16
+load r16 0 r17
+add r17 r1 r18
+add r18 r1 r19
+loadc 4 r20
+#Loop
+subtract r20 r1 r20
+jnz r20 #Loop
+halt
+
+align 0x40
+#Data
+data 41
+`))
+	x := m.Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(42), x.ReadRegister(flamego.R18))
+	assert.Equal(t, uint64(43), x.ReadRegister(flamego.R19))
+	assert.Equal(t, uint64(0), x.ReadRegister(flamego.R20))
+
+	s := m.Processor.Core(0).(*vm.PipelinedCore).Statistics()
+	// r17 is used immediately after being loaded
+	assert.Equal(t, uint64(1), s.LoadUseStalls)
+	// r16 and r18 are read immediately after being written, r17 is read after the load-use stall, and r20 is read by the first subtract
+	assert.GreaterOrEqual(t, s.Forwards, uint64(4))
+	// Jump predicted not taken the first time, and taken the last time
+	assert.Equal(t, uint64(4), s.Predictions)
+	assert.Equal(t, uint64(2), s.Mispredictions)
+	assert.NotZero(t, s.MemoryStalls)
+}
+
+func TestPipelinedCore_Error(t *testing.T) {
+	_, machines := assertSameAsBarrel(t, vm.Config{Core: vm.CorePipelined}, map[string]string{
+		"error": errorProgram,
+	})
+	x := machines["error"].Processor.Core(0).Context(0)
+	assert.Equal(t, uint64(7), x.ReadRegister(flamego.R16))
+	assert.Equal(t, uint64(0), x.ReadRegister(flamego.R18))
+	assert.Equal(t, uint64(flamego.InterruptRegisterAccessError), x.ReadRegister(flamego.R19))
+}
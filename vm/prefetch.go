@@ -0,0 +1,158 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+)
+
+// Prefetcher selects the speculative reads a cache issues to its lower store while it is idle.
+type Prefetcher int
+
+const (
+	// No speculative reads
+	PrefetchNone Prefetcher = iota
+	// Reads the line following a miss, or following the first read of a prefetched line
+	PrefetchNextLine
+	// Reads the line a stride ahead, once the same stride is seen between consecutive reads
+	PrefetchStride
+)
+
+func (p Prefetcher) String() string {
+	switch p {
+	case PrefetchNone:
+		return "none"
+	case PrefetchNextLine:
+		return "nextline"
+	case PrefetchStride:
+		return "stride"
+	}
+	return "unknown"
+}
+
+func ParsePrefetcher(s string) (Prefetcher, error) {
+	switch s {
+	case "none":
+		return PrefetchNone, nil
+	case "nextline":
+		return PrefetchNextLine, nil
+	case "stride":
+		return PrefetchStride, nil
+	}
+	return 0, fmt.Errorf("Unrecognized Prefetcher: %s", s)
+}
+
+// SetPrefetcher sets the prefetcher of the cache.
+func (c *Cache) SetPrefetcher(prefetcher Prefetcher) {
+	c.prefetcher = prefetcher
+}
+
+func (c *Cache) Prefetcher() Prefetcher {
+	return c.prefetcher
+}
+
+// train observes a read of the address, which hit a line that was prefetched, hit, or missed, and chooses the next address to prefetch.
+func (c *Cache) train(address uint64, hit, prefetched bool) {
+	unit := uint64(c.unit())
+	switch c.prefetcher {
+	case PrefetchNextLine:
+		if !hit || prefetched {
+			c.prefetch(address - address%unit + unit)
+		}
+	case PrefetchStride:
+		stride := int64(address - c.lastAddress)
+		if stride == 0 {
+			// Read is retried
+			return
+		}
+		if stride == c.stride && (stride > 0 || uint64(-stride)+unit <= address) {
+			target := address + uint64(stride)
+			if target/unit == address/unit {
+				// Stride is within the unit, so read the unit after in the same direction
+				target = address - address%unit + unit
+				if stride < 0 {
+					target = address - address%unit - unit
+				}
+			}
+			c.prefetch(target)
+		}
+		c.stride = stride
+		c.lastAddress = address
+	}
+}
+
+// prefetch holds the unit of the address to read from the lower store once it is idle, unless the cache holds it.
+func (c *Cache) prefetch(address uint64) {
+	unit := c.unit()
+	address -= address % uint64(unit)
+	tag, index, offset := c.ParseAddress(address)
+	line := c.lines[index]
+	if line.tag == tag {
+		held := true
+		for j := int(offset); j < int(offset)+unit && j < c.lineWidth; j++ {
+			if !line.IsValid(j) {
+				held = false
+				break
+			}
+		}
+		if held {
+			return
+		}
+	}
+	if (c.hasPrefetch || c.isPrefetching) && c.prefetchAddress == address {
+		// Already prefetching
+		return
+	}
+	c.prefetchAddress = address
+	c.prefetchLength = unit
+	c.hasPrefetch = true
+	c.isPrefetchIssued = false
+}
+
+// issuePrefetch reads the held prefetch from the lower store.
+func (c *Cache) issuePrefetch() {
+	if c.lower.IsBusy() || !c.lower.IsFree() {
+		return
+	}
+	c.hasPrefetch = false
+	c.isPrefetching = true
+	if !c.isPrefetchIssued {
+		// Retries are counted once
+		c.statistics.Prefetches++
+		c.isPrefetchIssued = true
+	}
+	c.lowerRequest(flamego.CacheRead, c.prefetchAddress, c.prefetchLength)
+}
+
+// prefetched fills a line from the lower bus with the read prefetch, unless the line holds dirty values of another address.
+func (c *Cache) prefetched() {
+	tag, index, offset := c.ParseAddress(c.lowerAddress)
+	line := c.lines[index]
+	if line.tag != tag {
+		if _, _, dirty := c.victim(line); dirty {
+			// Dirty values are not written back for a speculative read
+			return
+		}
+		c.evicted(line)
+		line.tag = tag
+		for i := 0; i < c.lineWidth; i++ {
+			line.SetValid(i, false)
+		}
+	}
+	lb := c.lower.Bus()
+	for i, j := 0, int(offset); i < lb.Length() && j < c.lineWidth; i, j = i+1, j+1 {
+		if lb.IsValid(i) && !(line.IsValid(j) && line.IsDirty(j)) {
+			line.Write(j, lb.Read(i))
+			line.SetDirty(j, false)
+		}
+	}
+	c.overlay(line, c.lowerAddress)
+	line.isPrefetched = true
+}
+
+// evicted counts a prefetched line which is repurposed before it was read.
+func (c *Cache) evicted(line *CacheLine) {
+	if line.isPrefetched {
+		c.statistics.Useless++
+		line.isPrefetched = false
+	}
+}
@@ -3,23 +3,79 @@ package vm
 import (
 	"aletheiaware.com/flamego"
 	"log"
+	"sync"
+	"sync/atomic"
 )
 
 func NewProcessor(cache flamego.Cache, memory flamego.Memory) *Processor {
-	return &Processor{
-		cache:      cache,
-		memory:     memory,
-		lockHolder: -1,
+	p := &Processor{
+		cache:         cache,
+		memory:        memory,
+		controller:    NewInterruptController(memory, flamego.InterruptControlBlockAddress),
+		mailboxes:     make([]*Mailbox, flamego.CoreCount*flamego.ContextCount),
+		locks:         make([]*Lock, flamego.LockCount),
+		lockThreshold: LockThreshold,
 	}
+	for i := range p.mailboxes {
+		p.mailboxes[i] = NewMailbox(flamego.MailboxSize)
+	}
+	for i := range p.locks {
+		p.locks[i] = NewLock(i)
+	}
+	p.controller.SetOnSignal(func(target int) {
+		p.Signal(flamego.InterruptControllerId, target)
+	})
+	p.controller.OnInterrupt = p.interrupt
+	return p
 }
 
 type Processor struct {
-	cores      []flamego.Core
-	cache      flamego.Cache
-	memory     flamego.Memory
-	devices    []flamego.Device
-	halted     bool
-	lockHolder int
+	cores            []flamego.Core
+	cache            flamego.Cache
+	memory           flamego.Memory
+	memoryController *MemoryController // Shares memory between the caches and devices, if set
+	devices          []flamego.Device
+	controller       *InterruptController
+	mailboxes        []*Mailbox
+	locks            []*Lock
+	requiredLocks    [flamego.CoreCount * flamego.ContextCount]uint64 // Bit set of locks required by each context
+	requested        [flamego.CoreCount]uint64                        // Bit set of locks newly required by the contexts of each core
+	activeLocks      uint64                                           // Bit set of locks required, held, or waited for
+	lockPolicy       flamego.LockPolicy
+	lockThreshold    int
+	deliveries       [flamego.CoreCount * flamego.ContextCount]flamego.Delivery // Outcome of the last message sent by each context
+	spawned          [flamego.CoreCount * flamego.ContextCount]bool             // Whether the last spawn by each context started its target
+	deferred         [flamego.CoreCount][]func()                                // Effects of each core on shared state, applied at the end of the cycle
+	clocking         bool
+	parallel         bool
+	workers          []*worker
+	barrier          sync.WaitGroup // Waits for every worker to finish a run
+	arrived          int32          // Count of workers which finished the cycle
+	generation       uint32         // Count of cycles settled by the workers, which wait for it to change
+	stopped          int32          // Whether the workers stop at the end of the cycle
+	clocked          int            // Cycles settled in the current run
+	fault            interface{}    // Panic raised while settling a cycle
+	halted           int32
+	cycle            uint64
+	epoch            uint64
+}
+
+// SetMemoryController clocks the controller in place of memory, and moves the interrupt controller to a DMA port.
+func (p *Processor) SetMemoryController(c *MemoryController) {
+	p.memoryController = c
+	p.controller.memory = c.AddPort(PortDMA)
+}
+
+func (p *Processor) MemoryController() *MemoryController {
+	return p.memoryController
+}
+
+// isMemoryBusy returns true while memory, or its controller, has a request to serve.
+func (p *Processor) isMemoryBusy() bool {
+	if p.memoryController != nil {
+		return p.memoryController.IsBusy()
+	}
+	return p.memory.IsBusy()
 }
 
 func (p *Processor) Cache() flamego.Cache {
@@ -38,40 +94,178 @@ func (p *Processor) Device(index int) flamego.Device {
 	return p.devices[index]
 }
 
+func (p *Processor) Devices() []flamego.Device {
+	return p.devices
+}
+
 func (p *Processor) AddDevice(d flamego.Device) {
+	id := flamego.CoreCount*flamego.ContextCount + len(p.devices)
 	p.devices = append(p.devices, d)
-	d.SetOnSignal(p.Signal)
+	d.SetOnSignal(func(target int) {
+		p.Signal(id, target)
+	})
+}
+
+func (p *Processor) InterruptController() flamego.InterruptController {
+	return p.controller
+}
+
+func (p *Processor) Cycle() uint64 {
+	return p.cycle
+}
+
+// Epoch is the time, in nanoseconds, at which the processor started.
+func (p *Processor) Epoch() uint64 {
+	return p.epoch
+}
+
+func (p *Processor) SetEpoch(epoch uint64) {
+	p.epoch = epoch
+}
+
+func (p *Processor) Time() uint64 {
+	return p.epoch + p.cycle*flamego.ClockPeriod
 }
 
 func (p *Processor) Halt() {
 	log.Println("Processor Halted")
-	p.halted = true
+	// Cores clocked in parallel may halt simultaneously
+	atomic.StoreInt32(&p.halted, 1)
 }
 
 func (p *Processor) HasHalted() bool {
-	return p.halted
+	return atomic.LoadInt32(&p.halted) != 0
 }
 
-func (p *Processor) LockHolder() int {
-	return p.lockHolder
+// Signal the target on behalf of the source.
+// Contexts are interrupted through the interrupt controller, devices are signalled directly.
+// Signals from contexts take effect at the end of the cycle.
+func (p *Processor) Signal(source, target int) {
+	p.schedule(source, func() {
+		p.signal(source, target)
+	})
 }
 
-func (p *Processor) Signal(device int) {
-	if device < flamego.CoreCount*flamego.ContextCount {
-		// Signal Core
-		core := device / flamego.CoreCount
-		context := device % flamego.ContextCount
-		p.cores[core].(*Core).contexts[context].Signal()
-	} else {
+func (p *Processor) signal(source, target int) {
+	if target >= 0 && target < flamego.CoreCount*flamego.ContextCount {
+		// Interrupt Context
+		p.controller.Raise(source, target)
+	} else if target == flamego.InterruptControllerId {
+		// Signal Interrupt Controller
+		p.controller.Signal()
+	} else if d := target - flamego.CoreCount*flamego.ContextCount; d >= 0 && d < len(p.devices) {
 		// Signal IO device
-		p.devices[device-flamego.CoreCount*flamego.ContextCount].Signal()
+		p.devices[d].Signal()
+	} else {
+		// Unrecognized targets are ignored, as the source may be servicing an interrupt
+		log.Println("Unrecognized Signal Target:", target)
+	}
+}
+
+func (p *Processor) interrupt(context int) {
+	if x := p.context(context); x != nil {
+		x.Signal()
 	}
 }
 
+func (p *Processor) Mailbox(context int) *Mailbox {
+	return p.mailboxes[context]
+}
+
+// Send delivers the message from the source context to the mailbox of the target context, waking the target if it is waiting for a message.
+// Messages sent while the cores are clocked are delivered at the end of the cycle.
+func (p *Processor) Send(source, target int, message uint64) {
+	p.schedule(source, func() {
+		x := p.context(target)
+		switch {
+		case x == nil:
+			p.deliveries[source] = flamego.MessageUndeliverable
+		case p.mailboxes[target].Push(message):
+			p.deliveries[source] = flamego.MessageDelivered
+			x.Wake()
+		default:
+			if p.deliveries[source] != flamego.MessageRejected {
+				// Retries of a rejected message are not counted again
+				p.mailboxes[target].Overflow()
+			}
+			p.deliveries[source] = flamego.MessageRejected
+		}
+	})
+}
+
+// Delivery returns the outcome of the last message sent by the context.
+func (p *Processor) Delivery(context int) flamego.Delivery {
+	return p.deliveries[context]
+}
+
+// Receive removes the next message from the mailbox of the context.
+// Returns false if the mailbox is empty.
+func (p *Processor) Receive(context int) (uint64, bool) {
+	return p.mailboxes[context].Pop()
+}
+
+// Spawn starts the target context with the given registers, recording the parent to be signalled when it exits.
+// Contexts spawned while the cores are clocked are started at the end of the cycle.
+func (p *Processor) Spawn(parent, target int, registers []uint64) {
+	registers = append([]uint64(nil), registers...)
+	p.schedule(parent, func() {
+		x := p.context(target)
+		p.spawned[parent] = x != nil && x.Spawn(parent, registers)
+	})
+}
+
+// Spawned returns false if the target of the last spawn by the context was not an idle context.
+func (p *Processor) Spawned(context int) bool {
+	return p.spawned[context]
+}
+
+// context returns the context with the given identifier, or nil if there is none, as cores may have fewer than ContextCount contexts.
+func (p *Processor) context(id int) flamego.Context {
+	if id < 0 || id/flamego.ContextCount >= len(p.cores) {
+		return nil
+	}
+	return p.cores[id/flamego.ContextCount].Context(id % flamego.ContextCount)
+}
+
 func (p *Processor) Clock(cycle int) {
+	p.cycle = uint64(cycle)
+	p.clockShared(cycle)
+
+	// Clock Each Core
+	p.clocking = true
+	if p.parallel {
+		p.clockParallel(cycle, cycle+1)
+	} else {
+		for _, c := range p.cores {
+			c.Clock(cycle)
+		}
+		p.clocking = false
+		p.settle(cycle)
+	}
+}
+
+// Run clocks the processor from the cycle until the next clock of the L2 caches, or until the processor halts, returning the number of cycles clocked.
+// Cores clocked in parallel run every cycle of the run after a single dispatch, otherwise a single cycle is clocked.
+func (p *Processor) Run(cycle int) int {
+	if !p.parallel {
+		p.Clock(cycle)
+		return 1
+	}
+	p.cycle = uint64(cycle)
+	p.clockShared(cycle)
+	p.clocking = true
+	return p.clockParallel(cycle, cycle-cycle%10+10)
+}
+
+// clockShared clocks memory and the caches shared by the contexts of each core, at the start of the cycle.
+func (p *Processor) clockShared(cycle int) {
 	// Main Memory is 1000 times slower
 	if cycle%1000 == 0 {
-		p.memory.Clock(cycle / 1000)
+		if p.memoryController != nil {
+			p.memoryController.Clock(cycle / 1000)
+		} else {
+			p.memory.Clock(cycle / 1000)
+		}
 	}
 
 	// L3 Caches are 100 times slower
@@ -79,31 +273,26 @@ func (p *Processor) Clock(cycle int) {
 		p.cache.Clock(cycle / 100)
 	}
 
-	// Clock Each Core
-	for _, c := range p.cores {
-		c.Clock(cycle)
+	// L2 Caches are 10 times slower, and clocked in core order as they share the L3 Cache
+	if cycle%10 == 0 {
+		for _, c := range p.cores {
+			c.Cache().Clock(cycle / 10)
+		}
 	}
+}
+
+// settle applies the effects of the cores on shared state, and clocks the devices and hardware locks, at the end of the cycle.
+func (p *Processor) settle(cycle int) {
+	p.applyDeferred()
 
 	// IO Devices are 5000 times slower
 	if cycle%5000 == 0 {
+		p.controller.Clock(cycle / 5000)
 		for _, d := range p.devices {
 			d.Clock(cycle / 5000)
 		}
 	}
 
-	// Update Hardware Lock
-	if p.lockHolder == -1 {
-		for i, c := range p.cores {
-			if c.RequiresLock() {
-				c.SetAcquiredLock(true)
-				p.lockHolder = i
-				break
-			}
-		}
-	} else {
-		if !p.cores[p.lockHolder].RequiresLock() {
-			p.cores[p.lockHolder].SetAcquiredLock(false)
-			p.lockHolder = -1
-		}
-	}
+	// Update Hardware Locks
+	p.updateLocks(cycle)
 }
@@ -0,0 +1,72 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newProcessor creates a processor with a single core
+func newProcessor(memory *vm.Memory) *vm.Processor {
+	l3Cache := vm.NewL3Cache(64*CacheSize, memory)
+	processor := vm.NewProcessor(l3Cache, memory)
+	l2Cache := vm.NewL2Cache(8*CacheSize, l3Cache)
+	core := vm.NewCore(0, processor, l2Cache)
+	processor.AddCore(core)
+	for i := 0; i < flamego.ContextCount; i++ {
+		core.AddContext(vm.NewContext(i, core, vm.NewL1Cache(CacheSize, l2Cache), vm.NewL1Cache(CacheSize, l2Cache)))
+	}
+	return processor
+}
+
+func TestProcessor_Signal(t *testing.T) {
+	processor := newProcessor(vm.NewMemory(MemorySize))
+
+	// Signals to devices that aren't attached are ignored
+	assert.NotPanics(t, func() {
+		processor.Signal(0, 80)
+		processor.Signal(0, -2)
+	})
+	assert.False(t, processor.InterruptController().IsPending(0))
+}
+
+func TestProcessor_Spawn(t *testing.T) {
+	processor := newProcessor(vm.NewMemory(MemorySize))
+	context := processor.Core(0).Context(1).(*vm.Context)
+	context.Signal()
+	assert.Equal(t, flamego.InterruptSourceHost, context.Parent())
+
+	registers := make([]uint64, flamego.SpawnDescriptorLength)
+	for i := range registers {
+		registers[i] = uint64(100 + i)
+	}
+
+	// Signalled context is not idle
+	processor.Spawn(0, 1, registers)
+	assert.False(t, processor.Spawned(0))
+
+	// Waiting context is not idle
+	context = processor.Core(0).Context(2).(*vm.Context)
+	context.Wait()
+	processor.Spawn(0, 2, registers)
+	assert.False(t, processor.Spawned(0))
+
+	// Unknown context
+	processor.Spawn(0, flamego.ContextCount, registers)
+	assert.False(t, processor.Spawned(0))
+
+	context = processor.Core(0).Context(3).(*vm.Context)
+	processor.Spawn(0, 3, registers)
+	assert.True(t, processor.Spawned(0))
+	assert.False(t, context.IsAsleep())
+	assert.False(t, context.IsInterrupted())
+	assert.Equal(t, 0, context.Parent())
+	for i := range registers {
+		assert.Equal(t, uint64(100+i), context.ReadRegister(flamego.R4+flamego.Register(i)))
+	}
+
+	// Awake context is not idle
+	processor.Spawn(0, 3, registers)
+	assert.False(t, processor.Spawned(0))
+}
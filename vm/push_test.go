@@ -0,0 +1,87 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// failingCache reports the first write as failed, after the cache has completed it, and records the values of the writes reported successful.
+type failingCache struct {
+	flamego.Cache
+	isWriting bool
+	hasFailed bool
+	value     uint64
+	writes    []uint64
+}
+
+func (c *failingCache) Write(address uint64) {
+	bus := c.Bus()
+	c.value = 0
+	for i := 0; i < flamego.DataSize; i++ {
+		c.value = c.value<<8 | uint64(bus.Read(i))
+	}
+	c.isWriting = true
+	c.Cache.Write(address)
+}
+
+func (c *failingCache) IsSuccessful() bool {
+	if c.isWriting && !c.hasFailed {
+		c.hasFailed = true
+		c.isWriting = false
+		return false
+	}
+	return c.Cache.IsSuccessful()
+}
+
+func (c *failingCache) Free() {
+	if c.isWriting {
+		c.writes = append(c.writes, c.value)
+		c.isWriting = false
+	}
+	c.Cache.Free()
+}
+
+func TestPush_Retry(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	l3Cache := vm.NewL3Cache(64*CacheSize, memory)
+	processor := vm.NewProcessor(l3Cache, memory)
+	l2Cache := vm.NewL2Cache(8*CacheSize, l3Cache)
+	core := vm.NewCore(0, processor, l2Cache)
+	processor.AddCore(core)
+	l1d := &failingCache{
+		Cache: vm.NewL1Cache(CacheSize, l2Cache),
+	}
+	core.AddContext(vm.NewContext(0, core, vm.NewL1Cache(CacheSize, l2Cache), l1d))
+	for i := 1; i < flamego.ContextCount; i++ {
+		core.AddContext(vm.NewContext(i, core, vm.NewL1Cache(CacheSize, l2Cache), vm.NewL1Cache(CacheSize, l2Cache)))
+	}
+	memory.Set(0, assemble(t, strings.NewReader(`
+loadc #StackStart rSP
+loadc #StackLimit rSL
+loadc 1 r16
+loadc 2 r17
+loadc 3 r18
+push r16,r17,r18
+halt
+align 0x40
+#StackStart
+allocate 4
+#StackLimit
+`)))
+	processor.Signal(flamego.InterruptSourceHost, 0)
+	for cycle := 0; !processor.HasHalted(); cycle++ {
+		if cycle > 1000000 {
+			t.Fatal("Processor never halted")
+		}
+		processor.Clock(cycle)
+	}
+
+	// Failed write of r16 was retried before the remaining registers were pushed
+	assert.True(t, l1d.hasFailed)
+	assert.Equal(t, []uint64{1, 2, 3}, l1d.writes)
+	x := core.Context(0)
+	assert.Equal(t, x.ReadRegister(flamego.RStackLimit)-flamego.DataSize, x.ReadRegister(flamego.RStackPointer))
+}
@@ -0,0 +1,172 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+)
+
+// Quiescent is implemented by components which can report when clocking them would have no effect.
+type Quiescent interface {
+	IsQuiescent() bool
+}
+
+// Scheduled is implemented by devices which, while quiescent, can report when they will next have an effect.
+type Scheduled interface {
+	// NextEvent returns the number of device clocks until the next event, or false if there is none
+	NextEvent() (uint64, bool)
+	// Skip advances the given number of device clocks, all before the next event
+	Skip(uint64)
+}
+
+// Skippable is implemented by cores which, while quiescent, can advance without being clocked.
+type Skippable interface {
+	Quiescent
+	Skip(int)
+}
+
+func isQuiescent(x interface{}) bool {
+	q, ok := x.(Quiescent)
+	return ok && q.IsQuiescent()
+}
+
+// nextMultiple returns the first cycle at or after the given cycle which is a multiple of the divider
+func nextMultiple(cycle, divider int) int {
+	if r := cycle % divider; r != 0 {
+		return cycle + divider - r
+	}
+	return cycle
+}
+
+func (x *Context) IsQuiescent() bool {
+	return x.isAsleep && !x.isSignalled && x.nextInterrupt < 0 && !x.isRetrying && isQuiescent(x.iCache) && isQuiescent(x.dCache)
+}
+
+// Skip accounts for cycles spent asleep in a barrel core without being clocked.
+func (x *Context) Skip(cycles int) {
+	if !x.isValid {
+		// Context only becomes valid once it reaches the fetch stage
+		stage := (x.core.(*Core).next - x.id + flamego.ContextCount) % flamego.ContextCount
+		fetch := (flamego.ContextCount - stage) % flamego.ContextCount
+		if cycles <= fetch {
+			return
+		}
+		x.isValid = true
+		cycles -= fetch
+	}
+	x.sleepCycles += cycles
+}
+
+func (c *Core) IsQuiescent() bool {
+	if !isQuiescent(c.cache) {
+		return false
+	}
+	for _, t := range c.threads {
+		if !t.context.IsQuiescent() {
+			return false
+		}
+		if c.policy != IssueRoundRobin && (t.inflight > 0 || t.retry != nil) {
+			// Pipeline is still draining
+			return false
+		}
+	}
+	return true
+}
+
+// Skip advances the core without clocking the pipeline.
+func (c *Core) Skip(cycles int) {
+	c.statistics.Cycles += uint64(cycles)
+	if c.policy != IssueRoundRobin {
+		// Pipeline is empty
+		for _, t := range c.threads {
+			t.context.sleepCycles += cycles
+		}
+		c.statistics.Empty += uint64(cycles)
+		return
+	}
+	for _, t := range c.threads {
+		t.context.Skip(cycles)
+	}
+	c.next = (c.next + cycles) % flamego.ContextCount
+	c.statistics.Idle += uint64(cycles)
+}
+
+func (c *Cache) IsQuiescent() bool {
+	return !c.isBusy && c.lowerOperation == flamego.CacheNone && !c.hasPrefetch && len(c.writes) == 0
+}
+
+func (d *Device) IsQuiescent() bool {
+	return !d.isBusy && d.memoryOperation == flamego.MemoryNone
+}
+
+func (t *Timer) NextEvent() (uint64, bool) {
+	return t.remaining, t.isArmed
+}
+
+func (t *Timer) Skip(clocks uint64) {
+	t.remaining -= clocks
+}
+
+// FastForward skips cycles in which clocking the processor would have no effect, returning the number of cycles skipped.
+// Cycles are only skipped when every context is asleep, the caches are idle, and no lock is in use.
+// The next event is the next memory clock if memory is busy, the next device clock if any device is busy,
+// otherwise the device clock of the next scheduled device event.
+func (p *Processor) FastForward(cycle int) int {
+	if p.activeLocks != 0 || !isQuiescent(p.cache) {
+		return 0
+	}
+	for _, c := range p.cores {
+		if v, ok := c.(Skippable); !ok || !v.IsQuiescent() {
+			return 0
+		}
+	}
+
+	// IO Devices are clocked every 5000 cycles
+	device := nextMultiple(cycle, 5000)
+
+	var target int
+	if p.isMemoryBusy() {
+		// Main Memory is clocked every 1000 cycles
+		target = nextMultiple(cycle, 1000)
+	} else if !isQuiescent(p.controller) || !p.areDevicesQuiescent() {
+		target = device
+	} else {
+		var next uint64
+		var scheduled []Scheduled
+		for _, d := range p.devices {
+			if s, ok := d.(Scheduled); ok {
+				if n, ok := s.NextEvent(); ok {
+					if next == 0 || n < next {
+						next = n
+					}
+					scheduled = append(scheduled, s)
+				}
+			}
+		}
+		if next == 0 {
+			// Nothing will happen
+			return 0
+		}
+		// Skip device clocks before the next event
+		for _, s := range scheduled {
+			s.Skip(next - 1)
+		}
+		target = device + int(next-1)*5000
+	}
+
+	skip := target - cycle
+	if skip <= 0 {
+		return 0
+	}
+	for _, c := range p.cores {
+		c.(Skippable).Skip(skip)
+	}
+	return skip
+}
+
+func (p *Processor) areDevicesQuiescent() bool {
+	for _, d := range p.devices {
+		if !isQuiescent(d) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/assembler"
+	"aletheiaware.com/flamego/vm"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const timerProgram = `
+acknowledge r16
+jlz r16 #Start
+add r17 r1 r17
+loadc 3 r18
+subtract r18 r17 r19
+jez r19 #Done
+sleep
+#Start
+loadc 64 r16
+signal r16
+sleep
+#Done
+halt
+`
+
+func TestProcessor_FastForward(t *testing.T) {
+	run := func(fastForward bool) (*vm.Processor, *vm.Memory, *vm.Timer, int, int) {
+		a := assembler.NewAssembler()
+		_, err := a.ReadFrom(strings.NewReader(timerProgram))
+		assert.NoError(t, err)
+		var program bytes.Buffer
+		_, err = a.WriteTo(&program)
+		assert.NoError(t, err)
+
+		memory := vm.NewMemory(MemorySize)
+		memory.Set(0, program.Bytes())
+		processor := newProcessor(memory)
+		timer := vm.NewTimer(memory, flamego.DeviceControlBlockAddress)
+		processor.AddDevice(timer)
+		// Periodic timer signalling context 0
+		setControlBlock(memory, flamego.DeviceControlBlockAddress, 0, flamego.DeviceWrite, 4, vm.TimerPeriodic, 0)
+		processor.Signal(flamego.InterruptSourceHost, 0)
+
+		cycle, skipped := 0, 0
+		for ; !processor.HasHalted(); cycle++ {
+			if cycle > 1000000 {
+				t.Fatal("Processor never halted")
+			}
+			if fastForward {
+				skip := processor.FastForward(cycle)
+				cycle += skip
+				skipped += skip
+			}
+			processor.Clock(cycle)
+		}
+		return processor, memory, timer, cycle, skipped
+	}
+
+	p1, m1, t1, c1, s1 := run(false)
+	p2, m2, t2, c2, s2 := run(true)
+	assert.Equal(t, 0, s1)
+	assert.Greater(t, s2, c2/2)
+
+	assert.Equal(t, c1, c2)
+	assert.Equal(t, m1.Get(0, MemorySize), m2.Get(0, MemorySize))
+	assert.Equal(t, t1.Remaining(), t2.Remaining())
+	for i := 0; i < flamego.ContextCount; i++ {
+		x1 := p1.Core(0).Context(i).(*vm.Context)
+		x2 := p2.Core(0).Context(i).(*vm.Context)
+		for r := flamego.R0; r <= flamego.R31; r++ {
+			assert.Equal(t, x1.ReadRegister(r), x2.ReadRegister(r))
+		}
+		assert.Equal(t, x1.SleepCycles(), x2.SleepCycles())
+		assert.Equal(t, x1.RetiredInstructions(), x2.RetiredInstructions())
+	}
+	assert.Equal(t, uint64(3), p1.Core(0).Context(0).ReadRegister(flamego.R17))
+}
@@ -2,65 +2,207 @@ package vm
 
 import (
 	"aletheiaware.com/flamego"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 )
 
+// StorageImage holds the bytes of a storage device.
+type StorageImage interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// Errors of a transfer, which is abandoned and the controller signalled.
+var (
+	ErrStorageReadOnly = errors.New("Storage is read-only")
+	ErrSectorAlignment = errors.New("Transfer is not aligned to sectors")
+	ErrStorageCapacity = errors.New("Transfer exceeds storage capacity")
+)
+
 var _ (flamego.Device) = (*FileStorage)(nil)
 
 func NewFileStorage(m flamego.Memory, o uint64) *FileStorage {
 	fs := &FileStorage{
-		Device: *NewDevice(m, o),
+		Device:     *NewDevice(m, o),
+		sectorSize: 1,
 	}
 	fs.AddOperation(flamego.DeviceStatus, fs.Status)
 	fs.AddOperation(flamego.DeviceEnable, fs.Enable)
 	fs.AddOperation(flamego.DeviceDisable, fs.Disable)
 	fs.AddOperation(flamego.DeviceRead, fs.Read)
 	fs.AddOperation(flamego.DeviceWrite, fs.Write)
+	fs.OnMemoryRead = fs.WriteFile
+	fs.OnStatus = fs.describe
+	fs.deviceType = flamego.DeviceTypeStorage
 	return fs
 }
 
 type FileStorage struct {
 	Device
-	file *os.File
+	file          *os.File
+	image         StorageImage
+	isReadOnly    bool
+	capacity      uint64
+	sectorSize    uint64
+	isTransfering bool  // Whether the current transfer has been checked
+	err           error // Error of the last transfer, cleared by Status
 }
 
+// File returns the image file, or the base image file of an overlay.
 func (s *FileStorage) File() *os.File {
 	return s.file
 }
 
+func (s *FileStorage) Image() StorageImage {
+	return s.image
+}
+
+func (s *FileStorage) IsReadOnly() bool {
+	return s.isReadOnly
+}
+
+// SetSectorSize sets the number of bytes in each sector, the address and length of each transfer must be a multiple of the sector size.
+// The default of 1 allows transfers of any bytes.
+func (s *FileStorage) SetSectorSize(size int) {
+	if size <= 0 || size&(size-1) != 0 {
+		panic(fmt.Errorf("Invalid sector size: %d", size))
+	}
+	s.sectorSize = uint64(size)
+}
+
+func (s *FileStorage) SectorSize() int {
+	return int(s.sectorSize)
+}
+
+// Capacity returns the number of bytes in the whole sectors of the image.
+func (s *FileStorage) Capacity() uint64 {
+	return s.capacity - s.capacity%s.sectorSize
+}
+
+// Error returns the error of the last transfer, or nil if none failed since the last Status.
+func (s *FileStorage) Error() error {
+	return s.err
+}
+
+// Open opens the image file read-only, writes to the storage fail.
 func (s *FileStorage) Open(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	s.file = f
+	return s.attach(f, f, true)
+}
+
+// OpenReadWrite opens the image file so writes to the storage are persisted in the file.
+func (s *FileStorage) OpenReadWrite(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	return s.attach(f, f, false)
+}
+
+// OpenOverlay opens the image file read-only, and the overlay file, created if it doesn't exist, so writes to the storage are persisted in the overlay.
+func (s *FileStorage) OpenOverlay(path, overlay string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	o, err := os.OpenFile(overlay, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	image, err := NewOverlay(f, o)
+	if err != nil {
+		f.Close()
+		o.Close()
+		return err
+	}
+	return s.attach(f, image, false)
+}
+
+// OpenMemory uses the buffer as the image, so writes to the storage are kept in the buffer.
+func (s *FileStorage) OpenMemory(buffer []byte) {
+	s.file = nil
+	s.image = NewMemoryImage(buffer)
+	s.isReadOnly = false
+	s.capacity = uint64(len(buffer))
+}
+
+// attach uses the image, whose capacity is the size of the file.
+func (s *FileStorage) attach(file *os.File, image StorageImage, readOnly bool) error {
+	info, err := file.Stat()
+	if err != nil {
+		image.Close()
+		return err
+	}
+	s.file = file
+	s.image = image
+	s.isReadOnly = readOnly
+	s.capacity = uint64(info.Size())
 	return nil
 }
 
 func (s *FileStorage) Close() error {
-	if s.file == nil {
+	if s.image == nil {
 		return nil
 	}
-	return s.file.Close()
+	return s.image.Close()
 }
 
-func (s *FileStorage) Status() error {
-	// TODO write to memory
-	// MemoryAddress[0]<-Manufacturer
-	// MemoryAddress[1]<-Current State
-	// MemoryAddress[2]<-Serial Number/Product ID
-	// MemoryAddress[3]<-Hardware Version
-	// MemoryAddress[4]<-Software Version
-	// MemoryAddress[5]<-Storage Size/Capacity
-	// MemoryAddress[6]
-	// MemoryAddress[7]
+// describe reports the capacity, sector size, and the error of the last transfer, which is cleared.
+func (s *FileStorage) describe(d *flamego.DeviceDescriptor) {
+	d.Capacity = s.Capacity()
+	d.Detail = s.sectorSize
+	switch s.err {
+	case nil:
+	case ErrStorageReadOnly:
+		d.Error = flamego.DeviceErrorReadOnly
+	case ErrSectorAlignment:
+		d.Error = flamego.DeviceErrorAlignment
+	case ErrStorageCapacity:
+		d.Error = flamego.DeviceErrorCapacity
+	default:
+		d.Error = flamego.DeviceErrorUnsupported
+	}
+	s.err = nil
+}
+
+// check returns the error of the transfer requested, if any, before its first bytes are transferred.
+func (s *FileStorage) check(write bool) error {
+	switch {
+	case write && s.isReadOnly:
+		return ErrStorageReadOnly
+	case s.deviceAddress%s.sectorSize != 0 || s.parameter%s.sectorSize != 0:
+		return ErrSectorAlignment
+	case s.deviceAddress+s.parameter < s.deviceAddress || s.deviceAddress+s.parameter > s.Capacity():
+		return ErrStorageCapacity
+	}
 	return nil
 }
 
+// fail abandons the transfer, recording the error and signalling the controller.
+func (s *FileStorage) fail(err error) {
+	log.Println("Storage Error:", err)
+	s.err = err
+	s.complete()
+}
+
+// complete signals the controller that the transfer has finished.
+func (s *FileStorage) complete() {
+	s.isTransfering = false
+	s.isBusy = false
+	s.operation = flamego.DeviceNone
+	s.SignalController()
+}
+
 func (s *FileStorage) Enable() error {
-	if s.file == nil {
+	if s.image == nil {
 		log.Println("Warning: Storage enabled without file being open")
 	}
 	s.isBusy = false
@@ -81,8 +223,12 @@ func (s *FileStorage) Disable() error {
 func (s *FileStorage) Read() error {
 	// Read from file into memory
 	if !s.memory.IsBusy() && s.memory.IsFree() {
-		if _, err := s.file.Seek(int64(s.deviceAddress), os.SEEK_SET); err != nil {
-			return err
+		if !s.isTransfering {
+			if err := s.check(false); err != nil {
+				s.fail(err)
+				return nil
+			}
+			s.isTransfering = true
 		}
 		mb := s.memory.Bus()
 		limit := s.parameter
@@ -90,11 +236,11 @@ func (s *FileStorage) Read() error {
 			limit = s
 		}
 		buffer := make([]byte, limit)
-		count, err := s.file.Read(buffer)
-		if err != nil {
-			return err
-		}
+		count, err := s.image.ReadAt(buffer, int64(s.deviceAddress))
 		if uint64(count) != limit {
+			if err != nil && err != io.EOF {
+				return err
+			}
 			return fmt.Errorf("Expected to read %d bytes from file, actually read %d", limit, count)
 		}
 		for i, b := range buffer {
@@ -107,20 +253,53 @@ func (s *FileStorage) Read() error {
 			s.memoryAddress += limit
 			s.parameter -= limit
 		} else {
-			s.isBusy = false
-			s.operation = flamego.DeviceNone
-			s.SignalController()
+			s.complete()
 		}
 	}
 	return nil
 }
 
 func (s *FileStorage) Write() error {
-	// Write from memory into file
+	// Read from memory, WriteFile writes into file
 	if !s.memory.IsBusy() && s.memory.IsFree() {
-		panic("Not Yet Implemented")
-		s.isBusy = false
-		s.operation = flamego.DeviceNone
+		if !s.isTransfering {
+			if err := s.check(true); err != nil {
+				s.fail(err)
+				return nil
+			}
+			s.isTransfering = true
+		}
+		if s.parameter == 0 {
+			s.complete()
+			return nil
+		}
+		limit := s.parameter
+		if s := uint64(s.memory.Bus().Size()); limit > s {
+			limit = s
+		}
+		s.memoryOperation = flamego.MemoryRead
+		s.memory.ReadBurst(s.memoryAddress, int(limit))
+	}
+	return nil
+}
+
+// WriteFile writes the bytes read from memory into the file, the next Write reads the remaining bytes or signals the controller.
+func (s *FileStorage) WriteFile() error {
+	mb := s.memory.Bus()
+	limit := mb.Length()
+	buffer := make([]byte, limit)
+	for i := range buffer {
+		buffer[i] = mb.Read(i)
+	}
+	count, err := s.image.WriteAt(buffer, int64(s.deviceAddress))
+	if err != nil {
+		return err
+	}
+	if count != limit {
+		return fmt.Errorf("Expected to write %d bytes to file, actually wrote %d", limit, count)
 	}
+	s.deviceAddress += uint64(limit)
+	s.memoryAddress += uint64(limit)
+	s.parameter -= uint64(limit)
 	return nil
 }
@@ -0,0 +1,178 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newStorageFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "storage.bin")
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// transfer signals the storage to perform the operation, clocking it until the controller is signalled.
+func transfer(t *testing.T, memory *vm.Memory, storage *vm.FileStorage, operation flamego.DeviceOperation, length, deviceAddress, memoryAddress uint64) {
+	t.Helper()
+	signalled := false
+	storage.SetOnSignal(func(int) {
+		signalled = true
+	})
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 0, operation, length, deviceAddress, memoryAddress)
+	storage.Signal()
+	for cycle := 0; !signalled || storage.MemoryOperation() != flamego.MemoryNone; cycle++ {
+		if cycle > 1000 {
+			t.Fatal("Storage never signalled")
+		}
+		clockDevice(memory, storage, cycle)
+	}
+}
+
+func TestFileStorage_ReadWrite(t *testing.T) {
+	path := newStorageFile(t, make([]byte, 64))
+	memory := vm.NewMemory(MemorySize)
+	storage := vm.NewFileStorage(memory, flamego.DeviceControlBlockAddress)
+	assert.Nil(t, storage.OpenReadWrite(path))
+	assert.False(t, storage.IsReadOnly())
+
+	memory.Set(1024, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	transfer(t, memory, storage, flamego.DeviceWrite, 12, 20, 1024)
+	transfer(t, memory, storage, flamego.DeviceRead, 12, 20, 2048)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, memory.Get(2048, 12))
+	assert.Nil(t, storage.Close())
+
+	// Write was persisted
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, data[20:32])
+	assert.Equal(t, 64, len(data))
+}
+
+func TestFileStorage_ReadOnly(t *testing.T) {
+	path := newStorageFile(t, make([]byte, 64))
+	memory := vm.NewMemory(MemorySize)
+	storage := vm.NewFileStorage(memory, flamego.DeviceControlBlockAddress)
+	assert.Nil(t, storage.Open(path))
+	assert.True(t, storage.IsReadOnly())
+	defer storage.Close()
+
+	memory.Set(1024, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	transfer(t, memory, storage, flamego.DeviceWrite, 8, 0, 1024)
+	assert.Equal(t, vm.ErrStorageReadOnly, storage.Error())
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, make([]byte, 64), data)
+}
+
+func TestFileStorage_Sectors(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	storage := vm.NewFileStorage(memory, flamego.DeviceControlBlockAddress)
+	buffer := make([]byte, 4*64+10)
+	storage.OpenMemory(buffer)
+	storage.SetSectorSize(64)
+	assert.Equal(t, 64, storage.SectorSize())
+	assert.Equal(t, uint64(4*64), storage.Capacity())
+	assert.Panics(t, func() {
+		storage.SetSectorSize(48)
+	})
+
+	// Status reports capacity and sector size
+	transfer(t, memory, storage, flamego.DeviceStatus, 0, 0, 2048)
+	descriptor := readDescriptor(memory, 2048)
+	assert.Equal(t, flamego.DeviceTypeStorage, descriptor.Type)
+	assert.Equal(t, uint64(4*64), descriptor.Capacity)
+	assert.Equal(t, uint64(64), descriptor.Detail)
+	assert.Equal(t, flamego.DeviceErrorNone, descriptor.Error)
+
+	for name, tt := range map[string]struct {
+		length, address uint64
+		err             error
+		code            flamego.DeviceError
+	}{
+		"Aligned": {
+			length:  64,
+			address: 128,
+		},
+		"UnalignedAddress": {
+			length:  64,
+			address: 8,
+			err:     vm.ErrSectorAlignment,
+			code:    flamego.DeviceErrorAlignment,
+		},
+		"UnalignedLength": {
+			length:  8,
+			address: 128,
+			err:     vm.ErrSectorAlignment,
+			code:    flamego.DeviceErrorAlignment,
+		},
+		"Capacity": {
+			length:  128,
+			address: 192,
+			err:     vm.ErrStorageCapacity,
+			code:    flamego.DeviceErrorCapacity,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := range buffer {
+				buffer[i] = 0
+			}
+			data := make([]byte, tt.length)
+			for i := range data {
+				data[i] = byte(i + 1)
+			}
+			memory.Set(4096, data)
+			transfer(t, memory, storage, flamego.DeviceWrite, tt.length, tt.address, 4096)
+			assert.Equal(t, tt.err, storage.Error())
+			if tt.err == nil {
+				assert.Equal(t, data, buffer[tt.address:tt.address+tt.length])
+			} else {
+				assert.Equal(t, make([]byte, len(buffer)), buffer)
+			}
+			transfer(t, memory, storage, flamego.DeviceRead, tt.length, tt.address, 8192)
+			assert.Equal(t, tt.err, storage.Error())
+
+			// Status reports, and clears, the error
+			transfer(t, memory, storage, flamego.DeviceStatus, 0, 0, 2048)
+			assert.Equal(t, tt.code, readDescriptor(memory, 2048).Error)
+			assert.Nil(t, storage.Error())
+		})
+	}
+}
+
+func TestFileStorage_Overlay(t *testing.T) {
+	base := make([]byte, 1024)
+	for i := range base {
+		base[i] = byte(i)
+	}
+	path := newStorageFile(t, base)
+	overlay := filepath.Join(t.TempDir(), "storage.cow")
+	memory := vm.NewMemory(MemorySize)
+	storage := vm.NewFileStorage(memory, flamego.DeviceControlBlockAddress)
+	assert.Nil(t, storage.OpenOverlay(path, overlay))
+
+	// Write spans the first two blocks
+	memory.Set(1024, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	transfer(t, memory, storage, flamego.DeviceWrite, 8, vm.OverlayBlockSize-4, 1024)
+	assert.Equal(t, 2, storage.Image().(*vm.Overlay).Blocks())
+	transfer(t, memory, storage, flamego.DeviceRead, 16, vm.OverlayBlockSize-8, 2048)
+	assert.Equal(t, []byte{0xf8, 0xf9, 0xfa, 0xfb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x04, 0x05, 0x06, 0x07}, memory.Get(2048, 16))
+	assert.Nil(t, storage.Close())
+
+	// Base image is unchanged
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, base, data)
+
+	// Overlay is reused when reopened
+	storage = vm.NewFileStorage(memory, flamego.DeviceControlBlockAddress)
+	assert.Nil(t, storage.OpenOverlay(path, overlay))
+	defer storage.Close()
+	assert.Equal(t, 2, storage.Image().(*vm.Overlay).Blocks())
+	transfer(t, memory, storage, flamego.DeviceRead, 8, vm.OverlayBlockSize-4, 3072)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, memory.Get(3072, 8))
+}
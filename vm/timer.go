@@ -0,0 +1,144 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"log"
+)
+
+const (
+	TimerOneShot  = 0
+	TimerPeriodic = 1
+)
+
+// Offsets of the registers of a timer in its MMIO window.
+const (
+	TimerRegisterRemaining = 0 // Read only
+	TimerRegisterPeriod    = flamego.DataSize
+	TimerWindowSize        = 2 * flamego.DataSize
+)
+
+var _ (flamego.Device) = (*Timer)(nil)
+var _ (MMIO) = (*Timer)(nil)
+
+func NewTimer(m flamego.Memory, o uint64) *Timer {
+	t := &Timer{
+		Device: *NewDevice(m, o),
+	}
+	t.AddOperation(flamego.DeviceStatus, t.Status)
+	t.AddOperation(flamego.DeviceEnable, t.Enable)
+	t.AddOperation(flamego.DeviceDisable, t.Disable)
+	t.AddOperation(flamego.DeviceWrite, t.Arm)
+	t.OnStatus = t.describe
+	t.deviceType = flamego.DeviceTypeTimer
+	return t
+}
+
+type Timer struct {
+	Device
+	isArmed    bool
+	isPeriodic bool
+	period     uint64
+	remaining  uint64
+	target     int
+}
+
+func (t *Timer) IsArmed() bool {
+	return t.isArmed
+}
+
+func (t *Timer) IsPeriodic() bool {
+	return t.isPeriodic
+}
+
+// Period is the number of timer clocks between expiries.
+func (t *Timer) Period() uint64 {
+	return t.period
+}
+
+// Remaining is the number of timer clocks until the next expiry.
+func (t *Timer) Remaining() uint64 {
+	return t.remaining
+}
+
+// Target is the identifier of the context signalled on expiry.
+func (t *Timer) Target() int {
+	return t.target
+}
+
+func (t *Timer) Clock(cycle int) {
+	if t.isArmed {
+		t.remaining--
+		if t.remaining == 0 {
+			t.Expire()
+		}
+	}
+	t.Device.Clock(cycle)
+}
+
+func (t *Timer) Expire() {
+	log.Println("Timer Expired, Signalling:", t.target)
+	if t.isPeriodic {
+		t.remaining = t.period
+	} else {
+		t.isArmed = false
+	}
+	t.OnSignal(t.target)
+}
+
+// describe reports the remaining timer clocks and the period.
+func (t *Timer) describe(d *flamego.DeviceDescriptor) {
+	d.Capacity = t.remaining
+	d.Detail = t.period
+}
+
+func (t *Timer) Enable() error {
+	t.isBusy = false
+	t.operation = flamego.DeviceNone
+	t.SignalController()
+	return nil
+}
+
+func (t *Timer) Disable() error {
+	// Cancel any pending expiry
+	t.isArmed = false
+	t.isBusy = false
+	t.operation = flamego.DeviceNone
+	return nil
+}
+
+// Arm schedules the controller to be signalled after parameter timer clocks.
+// The timer rearms itself after each expiry if bit 0 of the device address is set.
+// A period of zero disarms the timer.
+func (t *Timer) Arm() error {
+	t.period = t.parameter
+	t.remaining = t.parameter
+	t.isArmed = t.parameter > 0
+	t.isPeriodic = t.deviceAddress&TimerPeriodic != 0
+	t.target = t.controller
+	log.Println("Timer Armed:", t.period, "Periodic:", t.isPeriodic)
+	t.isBusy = false
+	t.operation = flamego.DeviceNone
+	return nil
+}
+
+// ReadMMIO returns the register of the timer at the offset, or zero if there is none.
+func (t *Timer) ReadMMIO(offset uint64) uint64 {
+	switch offset {
+	case TimerRegisterRemaining:
+		return t.remaining
+	case TimerRegisterPeriod:
+		return t.period
+	}
+	return 0
+}
+
+// WriteMMIO writes the register of the timer at the offset, writing the period rearms the timer for the same target, and a period of zero disarms it.
+func (t *Timer) WriteMMIO(offset, value uint64) {
+	switch offset {
+	case TimerRegisterPeriod:
+		t.period = value
+		t.remaining = value
+		t.isArmed = value > 0
+		log.Println("Timer Armed:", t.period, "Periodic:", t.isPeriodic)
+	}
+}
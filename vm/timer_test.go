@@ -0,0 +1,107 @@
+package vm_test
+
+import (
+	"aletheiaware.com/flamego"
+	"aletheiaware.com/flamego/vm"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTimer_OneShot(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	timer := vm.NewTimer(memory, flamego.DeviceControlBlockAddress)
+	var signals []int
+	timer.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+
+	// Arm timer to signal context 3 after 10 clocks
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 3, flamego.DeviceWrite, 10, vm.TimerOneShot, 0)
+	timer.Signal()
+
+	cycle := clockUntilArmed(t, memory, timer)
+	assert.Equal(t, uint64(10), timer.Remaining())
+
+	for i := 0; i < 9; i++ {
+		clockDevice(memory, timer, cycle)
+		cycle++
+	}
+	assert.Empty(t, signals)
+
+	clockDevice(memory, timer, cycle)
+	assert.Equal(t, []int{3}, signals)
+	assert.False(t, timer.IsArmed())
+
+	// One-shot timer shouldn't expire again
+	for i := 0; i < 20; i++ {
+		clockDevice(memory, timer, cycle)
+		cycle++
+	}
+	assert.Equal(t, []int{3}, signals)
+}
+
+func TestTimer_Periodic(t *testing.T) {
+	memory := vm.NewMemory(MemorySize)
+	timer := vm.NewTimer(memory, flamego.DeviceControlBlockAddress)
+	var signals []int
+	timer.SetOnSignal(func(c int) {
+		signals = append(signals, c)
+	})
+
+	// Arm timer to signal context 5 every 4 clocks
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 5, flamego.DeviceWrite, 4, vm.TimerPeriodic, 0)
+	timer.Signal()
+
+	cycle := clockUntilArmed(t, memory, timer)
+
+	for i := 0; i < 12; i++ {
+		clockDevice(memory, timer, cycle)
+		cycle++
+	}
+	assert.Equal(t, []int{5, 5, 5}, signals)
+	assert.True(t, timer.IsArmed())
+
+	// Disable timer to cancel expiry
+	setControlBlock(memory, flamego.DeviceControlBlockAddress, 5, flamego.DeviceDisable, 0, 0, 0)
+	timer.Signal()
+	for timer.IsBusy() {
+		clockDevice(memory, timer, cycle)
+		cycle++
+	}
+	assert.False(t, timer.IsArmed())
+	count := len(signals)
+
+	for i := 0; i < 12; i++ {
+		clockDevice(memory, timer, cycle)
+		cycle++
+	}
+	assert.Equal(t, count, len(signals))
+}
+
+func setControlBlock(memory *vm.Memory, address uint64, controller int, operation flamego.DeviceOperation, parameter, deviceAddress, memoryAddress uint64) {
+	buffer := make([]byte, flamego.DeviceControlBlockSize)
+	command := uint64(controller)<<56 | uint64(operation)<<48 | parameter
+	binary.BigEndian.PutUint64(buffer[0:], command)
+	binary.BigEndian.PutUint64(buffer[8:], deviceAddress)
+	binary.BigEndian.PutUint64(buffer[16:], memoryAddress)
+	memory.Set(address, buffer)
+}
+
+func clockDevice(memory *vm.Memory, device flamego.Device, cycle int) {
+	memory.Clock(cycle)
+	device.Clock(cycle)
+}
+
+func clockUntilArmed(t *testing.T, memory *vm.Memory, timer *vm.Timer) int {
+	t.Helper()
+	cycle := 0
+	for ; timer.IsBusy(); cycle++ {
+		if cycle > 100 {
+			t.Fatal("Timer never armed")
+		}
+		clockDevice(memory, timer, cycle)
+	}
+	assert.True(t, timer.IsArmed())
+	return cycle
+}
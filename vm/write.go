@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"aletheiaware.com/flamego"
+	"fmt"
+	"strings"
+)
+
+// WritePolicy selects how a cache handles writes, the zero value is write-back with write-allocate.
+type WritePolicy struct {
+	// Through writes each write to the lower store as well as to the line, otherwise dirty values are written back when the line is evicted or flushed
+	Through bool
+	// NoAllocate writes a write which misses to the lower store without allocating a line
+	NoAllocate bool
+	// Buffer is the number of writes held while they are written to the lower store, zero holds a single write and retries any other until it is written
+	Buffer int
+}
+
+func (p WritePolicy) String() string {
+	s := "back"
+	if p.Through {
+		s = "through"
+	}
+	if p.NoAllocate {
+		s += "+noallocate"
+	}
+	return s
+}
+
+// ParseWritePolicy parses "back" or "through", optionally followed by "+noallocate".
+func ParseWritePolicy(s string) (WritePolicy, error) {
+	var p WritePolicy
+	parts := strings.Split(s, "+")
+	switch parts[0] {
+	case "back":
+	case "through":
+		p.Through = true
+	default:
+		return p, fmt.Errorf("Unrecognized Write Policy: %s", s)
+	}
+	for _, part := range parts[1:] {
+		switch part {
+		case "noallocate":
+			p.NoAllocate = true
+		default:
+			return p, fmt.Errorf("Unrecognized Write Policy: %s", s)
+		}
+	}
+	return p, nil
+}
+
+// SetWritePolicy sets the write policy of the cache.
+func (c *Cache) SetWritePolicy(policy WritePolicy) {
+	if policy.Buffer < 0 {
+		panic("Invalid write buffer size")
+	}
+	c.writePolicy = policy
+}
+
+func (c *Cache) WritePolicy() WritePolicy {
+	return c.writePolicy
+}
+
+// Writes returns the addresses of the writes held in the write buffer.
+func (c *Cache) Writes() []uint64 {
+	var addresses []uint64
+	for _, w := range c.writes {
+		addresses = append(addresses, w.address)
+	}
+	return addresses
+}
+
+// writeRegister holds a write until it is written to the lower store.
+type writeRegister struct {
+	address uint64
+	bus     *Bus
+}
+
+// write holds the dirty values of the bus to be written to the lower store, returning false if the write buffer is full.
+func (c *Cache) write(address uint64) bool {
+	size := c.writePolicy.Buffer
+	if size == 0 {
+		size = 1
+	}
+	if len(c.writes) == size {
+		// Writer will retry
+		c.statistics.Stalled++
+		return false
+	}
+	length := c.bus.Length()
+	bus := NewBus(length)
+	for i := 0; i < length; i++ {
+		if c.bus.IsValid(i) && c.bus.IsDirty(i) {
+			bus.Write(i, c.bus.Read(i))
+		}
+	}
+	c.writes = append(c.writes, writeRegister{
+		address: address,
+		bus:     bus,
+	})
+	c.statistics.Buffered++
+	return true
+}
+
+// lowerWriteBuffered writes the oldest held write to the lower store.
+func (c *Cache) lowerWriteBuffered() {
+	if c.lower.IsBusy() || !c.lower.IsFree() {
+		return
+	}
+	w := c.writes[0]
+	length := w.bus.Length()
+	lb := c.lower.Bus()
+	lb.SetLength(length)
+	for i := 0; i < length; i++ {
+		if w.bus.IsValid(i) {
+			lb.Write(i, w.bus.Read(i))
+		} else {
+			lb.SetValid(i, false)
+		}
+	}
+	c.isDraining = true
+	c.lowerRequest(flamego.CacheWrite, w.address, length)
+}
+
+// overlay copies the held writes into the line filled from the given address, so values read from the lower store before they were written are replaced.
+func (c *Cache) overlay(line *CacheLine, address uint64) {
+	tag, index, _ := c.ParseAddress(address)
+	for _, w := range c.writes {
+		for i := 0; i < w.bus.Length(); i++ {
+			if !w.bus.IsValid(i) {
+				continue
+			}
+			t, x, o := c.ParseAddress(w.address + uint64(i))
+			if t == tag && x == index {
+				line.Write(int(o), w.bus.Read(i))
+			}
+		}
+	}
+}